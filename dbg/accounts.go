@@ -0,0 +1,129 @@
+package dbg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+var (
+	authHosts = []string{"login.microsoftonline.com", "xboxlive.com", "minecraftservices.com"}
+	// The app hasn't written an accounts file yet, so no account was added
+	errNoProfiles = errors.New("profiles/mc-accounts.json not found")
+)
+
+// diagnoseLogin combines the stored account metadata with the reachability of
+// the Microsoft, Xbox and Minecraft auth endpoints into a single verdict.
+// profilesErr is the error getProfiles returned, if any.
+func diagnoseLogin(profiles Profiles, profilesErr error, nc []NetworkCheck) LoginDiagnostics {
+	now := time.Now()
+	diag := LoginDiagnostics{
+		ActiveProfileExists: isActiveProfileInProfiles(profiles),
+		AuthReachable:       true,
+	}
+	for _, p := range profiles.Profiles {
+		account := AccountDiagnostic{
+			ID:          pseudonymise(p.UUID),
+			Active:      p.UUID == profiles.ActiveProfile,
+			NotLoggedIn: p.NotLoggedIn,
+		}
+		if p.MinecraftAccessTokenExpiresAt > 0 {
+			expires := epochTime(int64(p.MinecraftAccessTokenExpiresAt))
+			account.MinecraftTokenExpiresAt = expires.Unix()
+			account.MinecraftTokenExpired = now.After(expires)
+		}
+		if p.MicrosoftAccessTokenExpiresAt > 0 {
+			expires := epochTime(int64(p.MicrosoftAccessTokenExpiresAt))
+			account.MicrosoftTokenExpiresAt = expires.Unix()
+			account.MicrosoftTokenExpired = now.After(expires)
+		}
+		if p.LastLogin > 0 {
+			account.DaysSinceLogin = int(now.Sub(epochTime(int64(p.LastLogin))).Hours() / 24)
+		}
+		diag.Accounts = append(diag.Accounts, account)
+	}
+
+	for _, n := range nc {
+		for _, host := range authHosts {
+			if strings.Contains(n.URL, host) && !n.Success {
+				diag.AuthReachable = false
+				diag.FailedEndpoints = append(diag.FailedEndpoints, n.URL)
+			}
+		}
+	}
+
+	diag.Verdict, diag.LoginProblem = loginVerdict(diag, profiles.ActiveProfile != "", profilesErr)
+	return diag
+}
+
+func loginVerdict(diag LoginDiagnostics, hasActiveProfile bool, profilesErr error) (string, bool) {
+	if !diag.AuthReachable {
+		return fmt.Sprintf("Login problem: %d Microsoft/Xbox/Minecraft auth endpoints are unreachable, logins will fail until this is fixed", len(diag.FailedEndpoints)), true
+	}
+	if profilesErr != nil && !errors.Is(profilesErr, errNoProfiles) {
+		return fmt.Sprintf("Login problem: the app's accounts file could not be read (%s), the app can't see any accounts until it is fixed or removed", profilesErr), true
+	}
+	if len(diag.Accounts) == 0 {
+		return "Login problem: no accounts have been added to the app", true
+	}
+	if !hasActiveProfile || !diag.ActiveProfileExists {
+		return "Login problem: the active account no longer exists, select or add an account in the app", true
+	}
+	for _, a := range diag.Accounts {
+		if !a.Active {
+			continue
+		}
+		if a.NotLoggedIn {
+			return "Login problem: the active account is signed out and needs to sign in again", true
+		}
+		if a.MinecraftTokenExpired && a.MicrosoftTokenExpired {
+			return "Both tokens of the active account have expired, the app will try to refresh them; if launching fails sign out and back in", false
+		}
+	}
+	return "No login problem detected", false
+}
+
+// epochTime accepts both second and millisecond timestamps
+func epochTime(v int64) time.Time {
+	if v > 1e12 {
+		return time.UnixMilli(v)
+	}
+	return time.Unix(v, 0)
+}
+
+// pseudonymise returns a stable short id so accounts can be told apart
+// without sharing their UUID
+func pseudonymise(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:4])
+}
+
+func printLoginDiagnostics(diag LoginDiagnostics) {
+	for _, a := range diag.Accounts {
+		var notes []string
+		if a.Active {
+			notes = append(notes, "active")
+		}
+		if a.NotLoggedIn {
+			notes = append(notes, "signed out")
+		}
+		if a.MinecraftTokenExpired {
+			notes = append(notes, "minecraft token expired")
+		}
+		if a.MicrosoftTokenExpired {
+			notes = append(notes, "microsoft token expired")
+		}
+		notes = append(notes, fmt.Sprintf("last login %d days ago", a.DaysSinceLogin))
+		pterm.Info.Printfln("Account %s: %s", a.ID, strings.Join(notes, ", "))
+	}
+	if diag.LoginProblem {
+		pterm.Error.Println(diag.Verdict)
+	} else {
+		pterm.Success.Println(diag.Verdict)
+	}
+}
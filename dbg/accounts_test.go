@@ -0,0 +1,32 @@
+package dbg
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDiagnoseLoginProfiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		verdict string
+	}{
+		{"no accounts file", fstest.MapFS{}, "no accounts have been added"},
+		{"corrupt accounts file", fstest.MapFS{"ftba/storage/mc-accounts.json": {Data: []byte("{")}}, "mc-accounts.json: unexpected end of JSON input"},
+		{"empty accounts", fstest.MapFS{"ftba/storage/mc-accounts.json": {Data: []byte(`{"profiles":[]}`)}}, "no accounts have been added"},
+		{"signed in", fstest.MapFS{"ftba/storage/mc-accounts.json": {Data: []byte(`{"profiles":[{"uuid":"a"}],"activeProfile":"a"}`)}}, "No login problem"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFixture(t, tt.files, Options{})
+			ftbApp.InstallLocation = fixturePath("ftba")
+
+			profiles, err := getProfiles()
+			diag := diagnoseLogin(profiles, err, nil)
+			if !strings.Contains(diag.Verdict, tt.verdict) {
+				t.Errorf("verdict %q, want it to mention %q", diag.Verdict, tt.verdict)
+			}
+		})
+	}
+}
@@ -1,20 +1,20 @@
 package dbg
 
 import (
+	"bufio"
 	"bytes"
 	"compress/gzip"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"ftb-debug/v2/shared"
 	"github.com/hashicorp/go-version"
 	"github.com/pterm/pterm"
-	"io"
-	"os"
+	"github.com/shirou/gopsutil/v3/mem"
 	"path/filepath"
 	"regexp"
-	"runtime"
+	"slices"
 	"sort"
+	"time"
 )
 
 func runAppChecks() {
@@ -27,12 +27,26 @@ func runAppChecks() {
 	// Validate bin folder exists
 	doesBinExist()
 
-	//TODO Add instance checking and settings file validation
 	err = loadAppSettings()
 	if err != nil {
 		failedToLoadSettings = true
 		pterm.Error.Println("Failed to load app settings:\n", err)
+		return
+	}
+
+	pterm.DefaultSection.WithLevel(2).Println("App settings")
+	totalMemory := 0
+	if memInfo, err := mem.VirtualMemory(); err == nil {
+		totalMemory = int(memInfo.Total / 1024 / 1024)
 	}
+	settingsProblems = validateAppSettings(ftbApp.Settings, totalMemory)
+	for _, problem := range settingsProblems {
+		pterm.Error.Println("Invalid setting", problem)
+	}
+	defaults := defaultAppSettings
+	defaults.InstanceLocation = filepath.Join(ftbApp.InstallLocation, "instances")
+	settingsDiff = diffAppSettings(ftbApp.Settings, defaults)
+	printSettingsDiff(settingsDiff)
 }
 
 func loadAppSettings() error {
@@ -40,9 +54,9 @@ func loadAppSettings() error {
 		var appSettings []byte
 		var err error
 		appSettingsPath := filepath.Join(ftbApp.InstallLocation, "storage", "settings.json")
-		doesAppSettingsExist := shared.DoesPathExist(appSettingsPath)
+		doesAppSettingsExist := pathExists(appSettingsPath)
 		if doesAppSettingsExist {
-			appSettings, err = os.ReadFile(appSettingsPath)
+			appSettings, err = platform.FS.ReadFile(appSettingsPath)
 			if err != nil {
 				pterm.Error.Println("Error reading settings.json:", err)
 			}
@@ -61,106 +75,219 @@ func loadAppSettings() error {
 	}
 }
 
-func getInstances() (map[string]Instances, []InstanceLogs, error) {
-	instancesExists := shared.DoesPathExist(ftbApp.Settings.InstanceLocation)
-	if instancesExists {
-		pterm.Info.Println("Instance Location: ", ftbApp.Settings.InstanceLocation)
-		instances, _ := os.ReadDir(filepath.Join(ftbApp.Settings.InstanceLocation))
-		pIM := make(map[string]Instances)
-		var instanceLogs []InstanceLogs
-		for _, instance := range instances {
-			name := instance.Name()
-			if instance.IsDir() {
-				if name != ".localCache" {
-					pterm.Info.Println("found instance: ", name)
-					var i Instance
-					data, err := os.ReadFile(filepath.Join(ftbApp.Settings.InstanceLocation, name, "instance.json"))
-					if err := json.Unmarshal(data, &i); err != nil {
-						pterm.Error.Printfln("error reading instance.json: %s", err.Error())
-						continue
-					} else {
-						pIM[i.UUID] = Instances{
-							Name:                       i.Name,
-							PackType:                   i.PackType,
-							PackId:                     i.ID,
-							PackVersion:                i.VersionID,
-							Version:                    i.Version,
-							UUID:                       i.UUID,
-							McVersion:                  i.McVersion,
-							MinMemory:                  i.MinMemory,
-							RecMemory:                  i.RecMemory,
-							Memory:                     i.Memory,
-							JvmArgs:                    i.JvmArgs,
-							ShellArgs:                  i.ShellArgs,
-							EmbeddedJre:                i.EmbeddedJre,
-							JrePath:                    i.JrePath,
-							ModLoader:                  i.ModLoader,
-							IsModified:                 i.IsModified,
-							IsImport:                   i.IsImport,
-							HasInstMods:                i.HasInstMods,
-							InstallComplete:            i.InstallComplete,
-							ReleaseChannel:             i.ReleaseChannel,
-							Locked:                     i.Locked,
-							PreventMetaModInjection:    i.PreventMetaModInjection,
-							Private:                    i.Private,
-							LastPlayed:                 i.LastPlayed,
-							PotentiallyBrokenDismissed: i.PotentiallyBrokenDismissed,
-						}
+// getInstances finds instances in the configured instance location, the
+// default location, locations mentioned in the app logs and any given on the
+// command line. Instances are de-duplicated by UUID, and those found outside
+// the configured location are reported as orphaned. appLogs are the app logs
+// getAppLogs uploaded.
+func getInstances(appLogs []appLog) (map[string]Instances, []InstanceLogs, error) {
+	configured := ftbApp.Settings.InstanceLocation
+	configErr := checkInstanceLocation(configured)
+	if configErr == nil {
+		pterm.Info.Println("Instance Location: ", configured)
+	} else {
+		pterm.Error.Println(configErr)
+	}
+
+	pIM := make(map[string]Instances)
+	var instanceLogs []InstanceLogs
+	var scanned []string
+	for _, root := range instanceRoots(configured, appLogs) {
+		cleaned := filepath.Clean(root.Path)
+		if slices.Contains(scanned, cleaned) {
+			continue
+		}
+		scanned = append(scanned, cleaned)
+		isConfigured := configErr == nil && cleaned == filepath.Clean(configured)
+		if !isConfigured {
+			pterm.Debug.Printfln("Searching %s for instances (%s)", root.Path, root.Source)
+		}
+		instanceLogs = append(instanceLogs, scanInstanceRoot(root.Path, isConfigured, pIM)...)
+	}
 
-						// Check for logs
-						logsPath := filepath.Join(ftbApp.Settings.InstanceLocation, name, "logs")
-						logs := make(map[string]string)
-						if shared.DoesPathExist(logsPath) {
-							logs, err = getInstanceLogs(logsPath)
-							if err != nil {
-								pterm.Error.Printfln("Error getting instance logs: %s", err.Error())
-							}
-						}
+	if len(pIM) == 0 && configErr != nil {
+		return pIM, instanceLogs, configErr
+	}
+	return pIM, instanceLogs, nil
+}
 
-						// Check for crash-reports
-						crashLogsPath := filepath.Join(ftbApp.Settings.InstanceLocation, name, "crash-reports")
-						crashLogs := make(map[string]string)
-						if shared.DoesPathExist(crashLogsPath) {
-							crashLogs, err = getInstanceLogs(crashLogsPath)
-							if err != nil {
-								pterm.Error.Printfln("Error getting instance crash logs: %s", err.Error())
-							}
-						}
-						instanceLogs = append(instanceLogs, InstanceLogs{
-							Created:   0,
-							Name:      i.Name,
-							UUID:      i.UUID,
-							McVersion: i.McVersion,
-							ModLoader: i.ModLoader,
-							Logs:      logs,
-							CrashLogs: crashLogs,
-						})
+// instanceRoots lists the directories that may contain instances, the
+// configured location first
+func instanceRoots(configured string, appLogs []appLog) []InstanceRoot {
+	var roots []InstanceRoot
+	if configured != "" {
+		roots = append(roots, InstanceRoot{Path: configured, Source: "settings.json"})
+	}
+	if ftbApp.InstallLocation != "" {
+		roots = append(roots, InstanceRoot{Path: filepath.Join(ftbApp.InstallLocation, "instances"), Source: "default location"})
+	}
+	for _, location := range instanceLocationsFromLogs(appLogs) {
+		roots = append(roots, InstanceRoot{Path: location, Source: "app logs"})
+	}
+	for _, dir := range options.InstanceDirs {
+		roots = append(roots, InstanceRoot{Path: dir, Source: "command line"})
+	}
+	return roots
+}
 
-					}
+// A path only starts at the start of a line, after whitespace or after a
+// quote, so "[main/INFO] Loading /home/me/instances" doesn't match from the
+// slash in the log prefix
+var instancesPathRe = regexp.MustCompile(`(?im)(?:^|[\s"'])((?:[a-z]:[\\/]|/)[^"'\r\n:*?<>|]*?[\\/]instances)(?:[\\/"'\s]|$)`)
 
-					_, err = validateJson(name+" instance.json", filepath.Join(ftbApp.Settings.InstanceLocation, name, "instance.json"))
-					if err != nil {
-						pterm.Error.Printfln("instance.json failed to validate: %s", err.Error())
-						continue
-					}
+// instanceLocationsFromLogs returns instance directories logged in appLogs,
+// reading them a line at a time
+func instanceLocationsFromLogs(appLogs []appLog) []string {
+	var locations []string
+	for _, log := range appLogs {
+		r, err := openLog(log.path)
+		if err != nil {
+			continue
+		}
+		br := bufio.NewReader(r)
+		for {
+			line, err := br.ReadString('\n')
+			for _, match := range instancesPathRe.FindAllStringSubmatch(line, -1) {
+				if !slices.Contains(locations, match[1]) {
+					locations = append(locations, match[1])
 				}
 			}
+			if err != nil {
+				break
+			}
+		}
+		_ = r.Close()
+	}
+	return locations
+}
+
+func scanInstanceRoot(root string, configured bool, pIM map[string]Instances) []InstanceLogs {
+	instances, err := platform.FS.ReadDir(root)
+	if err != nil {
+		return nil
+	}
+	var instanceLogs []InstanceLogs
+	for _, instance := range instances {
+		name := instance.Name()
+		if !instance.IsDir() || name == ".localCache" {
+			continue
+		}
+		instanceJson := filepath.Join(root, name, "instance.json")
+		if !configured && !pathExists(instanceJson) {
+			continue
+		}
+		var i Instance
+		data, err := platform.FS.ReadFile(instanceJson)
+		if err := json.Unmarshal(data, &i); err != nil {
+			pterm.Error.Printfln("error reading instance.json: %s", err.Error())
+			continue
+		}
+		if existing, ok := pIM[i.UUID]; ok {
+			pterm.Debug.Printfln("Skipping duplicate of %s at %s", existing.Name, filepath.Join(root, name))
+			continue
+		}
+		if configured {
+			pterm.Info.Println("found instance: ", name)
+		} else {
+			pterm.Warning.Printfln("found orphaned instance %s (%s) outside the configured instance location: %s", i.Name, name, root)
+		}
+		integrity := verifyInstance(filepath.Join(root, name), root, i)
+		if integrity != nil {
+			printIntegrityReport(i.Name, i.InstallComplete, integrity)
+		}
+		size, worldSizes := instanceSize(filepath.Join(root, name))
+		worlds := listWorlds(filepath.Join(root, name), worldSizes)
+		printInstanceInventory(i.Name, size, worlds)
+		pIM[i.UUID] = Instances{
+			Name:                       i.Name,
+			PackType:                   i.PackType,
+			PackId:                     i.ID,
+			PackVersion:                i.VersionID,
+			Version:                    i.Version,
+			UUID:                       i.UUID,
+			McVersion:                  i.McVersion,
+			MinMemory:                  i.MinMemory,
+			RecMemory:                  i.RecMemory,
+			Memory:                     i.Memory,
+			JvmArgs:                    i.JvmArgs,
+			ShellArgs:                  i.ShellArgs,
+			EmbeddedJre:                i.EmbeddedJre,
+			JrePath:                    i.JrePath,
+			ModLoader:                  i.ModLoader,
+			IsModified:                 i.IsModified,
+			IsImport:                   i.IsImport,
+			HasInstMods:                i.HasInstMods,
+			InstallComplete:            i.InstallComplete,
+			ReleaseChannel:             i.ReleaseChannel,
+			Locked:                     i.Locked,
+			PreventMetaModInjection:    i.PreventMetaModInjection,
+			Private:                    i.Private,
+			LastPlayed:                 i.LastPlayed,
+			PotentiallyBrokenDismissed: i.PotentiallyBrokenDismissed,
+			Location:                   root,
+			Orphaned:                   !configured,
+			Integrity:                  integrity,
+			Size:                       size,
+			Worlds:                     worlds,
+		}
+
+		// Check for logs
+		logsPath := filepath.Join(root, name, "logs")
+		logs := make(map[string]string)
+		var keptLogs map[string]keptLog
+		if pathExists(logsPath) {
+			logs, keptLogs, err = getInstanceLogs(logsPath, false, crashExcerptLogs)
+			if err != nil {
+				pterm.Error.Printfln("Error getting instance logs: %s", err.Error())
+			}
+		}
+
+		// Check for crash-reports
+		crashLogsPath := filepath.Join(root, name, "crash-reports")
+		crashLogs := make(map[string]string)
+		var reports map[string]keptLog
+		if pathExists(crashLogsPath) {
+			crashLogs, reports, err = getInstanceLogs(crashLogsPath, true, nil)
+			if err != nil {
+				pterm.Error.Printfln("Error getting instance crash logs: %s", err.Error())
+			}
+		}
+		var excerpts map[string][]CrashExcerpt
+		var times map[string]time.Time
+		if len(crashLogs) > 0 {
+			times = crashTimes(reports)
+			excerpts = crashExcerpts(keptLogs, times)
+		}
+		instanceLogs = append(instanceLogs, InstanceLogs{
+			Created:       0,
+			Name:          i.Name,
+			UUID:          i.UUID,
+			McVersion:     i.McVersion,
+			ModLoader:     i.ModLoader,
+			Logs:          logs,
+			CrashLogs:     crashLogs,
+			CrashExcerpts: excerpts,
+			crashTimes:    times,
+		})
+
+		_, err = validateJson(name+" instance.json", instanceJson)
+		if err != nil {
+			pterm.Error.Printfln("instance.json failed to validate: %s", err.Error())
 		}
-		return pIM, instanceLogs, nil
 	}
-	return nil, nil, errors.New("instances directory not found")
+	return instanceLogs
 }
 
 // NEW STUFF HERE
 
 func getAppVersion() (AppMeta, error) {
 	var metaPath string
-	if runtime.GOOS == "windows" {
-		metaPath = filepath.Join(windowsAppPath, "resources", "meta.json")
+	if platform.GOOS == "windows" {
+		metaPath = filepath.Join(platform.WindowsAppPath, "resources", "meta.json")
 
 		// checking overwolf
 		var rawVersions []string
-		files, err := os.ReadDir(overwolfAppPath)
+		files, err := platform.FS.ReadDir(platform.OverwolfAppPath)
 		if err == nil {
 			for _, file := range files {
 				if file.IsDir() {
@@ -178,26 +305,26 @@ func getAppVersion() (AppMeta, error) {
 					return versions[i].GreaterThan(versions[j])
 				})
 				pterm.Debug.Println("Found versions:", versions)
-				if !shared.DoesPathExist(metaPath) {
-					metaPath = filepath.Join(overwolfAppPath, versions[0].String(), "meta.json")
+				if !pathExists(metaPath) {
+					metaPath = filepath.Join(platform.OverwolfAppPath, versions[0].String(), "meta.json")
 				}
 			}
 		}
-	} else if runtime.GOOS == "darwin" {
-		metaPath = filepath.Join(macAppPath, "contents", "Resources", "meta.json")
-	} else if runtime.GOOS == "linux" {
+	} else if platform.GOOS == "darwin" {
+		metaPath = filepath.Join(platform.MacAppPath, "contents", "Resources", "meta.json")
+	} else if platform.GOOS == "linux" {
 		return AppMeta{}, errors.New("linux not supported yet")
 	} else {
 		return AppMeta{}, errors.New("unknown OS, could you let us know what operating system you are using so we can add our checks")
 	}
 
-	installExists := shared.DoesPathExist(metaPath)
+	installExists := pathExists(metaPath)
 	if !installExists {
 		return AppMeta{}, errors.New("app meta not found")
 	}
 
 	// Read json file
-	metaRaw, err := os.ReadFile(metaPath)
+	metaRaw, err := platform.FS.ReadFile(metaPath)
 	if err != nil {
 		return AppMeta{}, err
 	}
@@ -210,116 +337,131 @@ func getAppVersion() (AppMeta, error) {
 
 func getProfiles() (Profiles, error) {
 	oldProfilesPath := filepath.Join(ftbApp.InstallLocation, "profiles.json")
-	oldProfilesExists := shared.DoesPathExist(oldProfilesPath)
+	oldProfilesExists := pathExists(oldProfilesPath)
 	if oldProfilesExists {
-		profilesRaw, err := os.ReadFile(oldProfilesPath)
+		profilesRaw, err := platform.FS.ReadFile(oldProfilesPath)
 		if err != nil {
 			return Profiles{}, err
 		}
 		var profiles Profiles
 		if err := json.Unmarshal(profilesRaw, &profiles); err != nil {
-			return Profiles{}, err
+			return Profiles{}, fmt.Errorf("%s: %w", filepath.Base(oldProfilesPath), err)
 		}
 		return profiles, nil
 	}
 	profilesPath := filepath.Join(ftbApp.InstallLocation, "storage", "mc-accounts.json")
-	profilesExists := shared.DoesPathExist(profilesPath)
+	profilesExists := pathExists(profilesPath)
 	if profilesExists {
-		profilesRaw, err := os.ReadFile(profilesPath)
+		profilesRaw, err := platform.FS.ReadFile(profilesPath)
 		if err != nil {
 			return Profiles{}, err
 		}
 		var profiles Profiles
 		if err := json.Unmarshal(profilesRaw, &profiles); err != nil {
-			return Profiles{}, err
+			return Profiles{}, fmt.Errorf("%s: %w", filepath.Base(profilesPath), err)
 		}
 		return profiles, nil
 	}
-	return Profiles{}, errors.New("profiles/mc-accounts.json not found")
+	return Profiles{}, errNoProfiles
 }
 
-func getAppLogs() (map[string]string, error) {
+// getAppLogs uploads the selected app logs. The uploaded logs are also
+// returned so crash excerpts can be cut from them without selecting again.
+func getAppLogs() (map[string]string, []appLog, error) {
 	lPath := filepath.Join(ftbApp.InstallLocation, "logs")
-	files, err := os.ReadDir(lPath)
+	files, err := selectLogs(lPath, []string{".log", ".txt", ".gz"}, false)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	logFile := make(map[string]string)
+	var uploaded []appLog
 	for _, file := range files {
-
-		re, err := regexp.Compile(`^([0-9]{4}-[0-9]{2}-[0-9]{2}).*`)
+		data, err := platform.FS.ReadFile(filepath.Join(lPath, file.Name()))
 		if err != nil {
-			fmt.Println("Error compiling regex:", err)
-			return nil, err
-		}
-
-		if re.MatchString(file.Name()) {
+			pterm.Error.Println("Error reading log file:", err)
 			continue
 		}
 
-		if filepath.Ext(file.Name()) == ".log" || filepath.Ext(file.Name()) == ".txt" || filepath.Ext(file.Name()) == ".gz" {
-			data, err := os.ReadFile(filepath.Join(lPath, file.Name()))
-			if err != nil {
-				pterm.Error.Println("Error reading log file:", err)
-				continue
-			}
-
-			if filepath.Ext(file.Name()) == ".gz" {
-				reader, err := gzip.NewReader(bytes.NewReader(data))
-				if err != nil {
-					return nil, err
-				}
-				data, err = io.ReadAll(reader)
-			}
-
-			if len(data) == 0 {
-				continue
-			}
-
-			request, err := uploadRequest(data, "log")
-			if err != nil {
-				pterm.Error.Println("Error creating upload request:", err)
+		var ref string
+		switch {
+		case filepath.Ext(file.Name()) == ".gz":
+			// Decompressed logs can be huge, only what fits the budget is read
+			reader, gzErr := gzip.NewReader(bytes.NewReader(data))
+			if gzErr != nil {
+				pterm.Error.Println("Error reading log file:", gzErr)
 				continue
 			}
-			logFile[file.Name()] = request.Data.ID
+			ref, err = uploads.addReader(file.Name(), reader, "log")
+		case file.Name() == "latest.log":
+			ref, err = uploads.addImportant(file.Name(), data, "log")
+		default:
+			ref, err = uploads.add(file.Name(), data, "log")
 		}
+		if errors.Is(err, errEmptyUpload) {
+			continue
+		}
+		if err != nil {
+			pterm.Error.Println("Error creating upload request:", err)
+			continue
+		}
+		logFile[file.Name()] = ref
+		log := appLog{path: filepath.Join(lPath, file.Name())}
+		if info, err := file.Info(); err == nil {
+			log.modified = info.ModTime()
+		}
+		uploaded = append(uploaded, log)
 	}
-	return logFile, nil
+	return logFile, uploaded, nil
 }
 
-func getInstanceLogs(path string) (map[string]string, error) {
-	files, err := os.ReadDir(path)
+// getInstanceLogs uploads the selected logs in path. keepNewest is set for
+// crash reports so the latest one is always included, crash reports and
+// latest.log are uploaded before the reserved part of the budget runs out.
+// The content of the logs named in keep, or of every log when keep is nil,
+// is returned so crash excerpts can be cut without reading them again.
+func getInstanceLogs(path string, keepNewest bool, keep []string) (map[string]string, map[string]keptLog, error) {
+	files, err := selectLogs(path, []string{".log", ".txt"}, keepNewest)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	logFile := make(map[string]string)
+	kept := make(map[string]keptLog)
 	for _, file := range files {
-		if filepath.Ext(file.Name()) == ".log" || filepath.Ext(file.Name()) == ".txt" {
-			data, err := os.ReadFile(filepath.Join(path, file.Name()))
-			if err != nil {
-				pterm.Error.Println("Error reading log file:", err)
-				continue
-			}
-			if len(data) == 0 {
-				continue
-			}
-			request, err := uploadRequest(data, "log")
-			if err != nil {
-				pterm.Error.Println("Error creating upload request:", err)
-				continue
+		data, err := platform.FS.ReadFile(filepath.Join(path, file.Name()))
+		if err != nil {
+			pterm.Error.Println("Error reading log file:", err)
+			continue
+		}
+		if len(data) == 0 {
+			continue
+		}
+		if keep == nil || slices.Contains(keep, file.Name()) {
+			k := keptLog{data: data}
+			if info, err := file.Info(); err == nil {
+				k.modified = info.ModTime()
 			}
-			logFile[file.Name()] = request.Data.ID
+			kept[file.Name()] = k
 		}
+		upload := uploads.add
+		if keepNewest || file.Name() == "latest.log" {
+			upload = uploads.addImportant
+		}
+		ref, err := upload(file.Name(), data, "log")
+		if err != nil {
+			pterm.Error.Println("Error creating upload request:", err)
+			continue
+		}
+		logFile[file.Name()] = ref
 	}
-	return logFile, nil
+	return logFile, kept, nil
 }
 
 func getMiscFile(path string) (string, error) {
-	exists := shared.DoesPathExist(path)
+	exists := pathExists(path)
 	if !exists {
 		return "", fmt.Errorf("file %s does not exist", path)
 	}
-	data, err := os.ReadFile(path)
+	data, err := platform.FS.ReadFile(path)
 	if err != nil {
 		return "", err
 	}
@@ -328,19 +470,12 @@ func getMiscFile(path string) (string, error) {
 		if err != nil {
 			return "", err
 		}
-		data, err = io.ReadAll(reader)
+		return uploads.addReader(filepath.Base(path), reader, "")
 	}
 
 	lang := ""
 	if filepath.Ext(path) == ".json" {
 		lang = "json"
 	}
-	if len(data) == 0 {
-		return "", errors.New("file is empty")
-	}
-	request, err := uploadRequest(data, lang)
-	if err != nil {
-		return "", err
-	}
-	return request.Data.ID, nil
+	return uploads.add(filepath.Base(path), data, lang)
 }
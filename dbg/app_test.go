@@ -0,0 +1,206 @@
+package dbg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"maps"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func instanceJSON(t *testing.T, i Instance) *fstest.MapFile {
+	t.Helper()
+	data, err := json.Marshal(i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fstest.MapFile{Data: data}
+}
+
+func TestGetAppLogs(t *testing.T) {
+	now := time.Now()
+	useFixture(t, fstest.MapFS{
+		"ftba/logs/latest.log":          {Data: []byte("started\n"), ModTime: now},
+		"ftba/logs/2024-01-01-1.log.gz": {Data: gzipped(t, "rotated\n"), ModTime: now.Add(-time.Hour)},
+		"ftba/logs/empty.log":           {ModTime: now},
+		"ftba/logs/old.log":             {Data: []byte("old\n"), ModTime: now.AddDate(0, 0, -30)},
+		"ftba/logs/broken.gz":           {Data: []byte("not gzip"), ModTime: now},
+	}, Options{LogCount: 5, LogDays: 7})
+	ftbApp.InstallLocation = fixturePath("ftba")
+
+	logs, kept, err := getAppLogs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"2024-01-01-1.log.gz", "latest.log"}
+	if got := slices.Sorted(maps.Keys(logs)); !slices.Equal(got, want) {
+		t.Fatalf("uploaded %v, want %v", got, want)
+	}
+	if len(kept) != len(want) {
+		t.Errorf("returned %v for crash excerpts, want %v", kept, want)
+	}
+	if got := uploaded(t, logs["2024-01-01-1.log.gz"]); got != "rotated\n" {
+		t.Errorf("gzipped log uploaded as %q", got)
+	}
+	if got := uploaded(t, logs["latest.log"]); got != "started\n" {
+		t.Errorf("latest.log uploaded as %q", got)
+	}
+
+	ftbApp.InstallLocation = fixturePath("missing")
+	if _, _, err := getAppLogs(); err == nil {
+		t.Error("expected an error for a missing log directory")
+	}
+}
+
+func TestScanInstanceRoot(t *testing.T) {
+	files := fstest.MapFS{
+		"instances/a/instance.json":                                      instanceJSON(t, Instance{UUID: "a", Name: "Pack A", McVersion: "1.20.1"}),
+		"instances/a/logs/latest.log":                                    {Data: []byte("[12:00:00] [main/INFO]: hello\n"), ModTime: time.Now()},
+		"instances/a/crash-reports/crash-2024-01-01_12.00.00-client.txt": {Data: []byte("---- Minecraft Crash Report ----\n"), ModTime: time.Now()},
+		"instances/b/instance.json":                                      instanceJSON(t, Instance{UUID: "b", Name: "Pack B"}),
+		"instances/copy-of-a/instance.json":                              instanceJSON(t, Instance{UUID: "a", Name: "Pack A copy"}),
+		"instances/not-an-instance/readme.txt":                           {Data: []byte("hi")},
+		"instances/broken/instance.json":                                 {Data: []byte("{")},
+		"instances/.localCache/instance.json":                            instanceJSON(t, Instance{UUID: "cache"}),
+		"instances/file.txt":                                             {Data: []byte("not a directory")},
+	}
+
+	tests := []struct {
+		name       string
+		configured bool
+		wantUUIDs  []string
+		wantLogs   int
+	}{
+		{"configured location", true, []string{"a", "b"}, 2},
+		{"other location", false, []string{"a", "b"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFixture(t, files, Options{LogCount: 5, LogDays: 7})
+			pIM := make(map[string]Instances)
+			logs := scanInstanceRoot(fixturePath("instances"), tt.configured, pIM)
+
+			if got := slices.Sorted(maps.Keys(pIM)); !slices.Equal(got, tt.wantUUIDs) {
+				t.Errorf("found %v, want %v", got, tt.wantUUIDs)
+			}
+			if len(logs) != tt.wantLogs {
+				t.Errorf("got %d instance logs, want %d", len(logs), tt.wantLogs)
+			}
+			for _, i := range pIM {
+				if i.Orphaned == tt.configured {
+					t.Errorf("%s orphaned = %t", i.Name, i.Orphaned)
+				}
+				if i.Location != fixturePath("instances") {
+					t.Errorf("%s location = %q", i.Name, i.Location)
+				}
+			}
+			if pIM["a"].Name != "Pack A" {
+				t.Errorf("duplicate replaced the first instance: %q", pIM["a"].Name)
+			}
+			for _, l := range logs {
+				if l.UUID != "a" {
+					continue
+				}
+				if _, ok := l.Logs["latest.log"]; !ok {
+					t.Errorf("latest.log not uploaded: %v", l.Logs)
+				}
+				if len(l.CrashLogs) != 1 {
+					t.Errorf("crash reports not uploaded: %v", l.CrashLogs)
+				}
+			}
+		})
+	}
+
+	useFixture(t, files, Options{})
+	if logs := scanInstanceRoot(fixturePath("missing"), true, make(map[string]Instances)); logs != nil {
+		t.Errorf("missing root returned %v", logs)
+	}
+}
+
+func TestGetInstances(t *testing.T) {
+	files := fstest.MapFS{
+		"games/instances/a/instance.json": instanceJSON(t, Instance{UUID: "a", Name: "Configured"}),
+		"ftba/instances/a/instance.json":  instanceJSON(t, Instance{UUID: "a", Name: "Duplicate"}),
+		"ftba/instances/b/instance.json":  instanceJSON(t, Instance{UUID: "b", Name: "Default location"}),
+		"ftba/logs/latest.log":            {Data: []byte("[main/INFO] Loading instances from " + fixturePath("old", "instances") + "\n"), ModTime: time.Now()},
+		"old/instances/c/instance.json":   instanceJSON(t, Instance{UUID: "c", Name: "Logged location"}),
+		"extra/instances/d/instance.json": instanceJSON(t, Instance{UUID: "d", Name: "Command line"}),
+	}
+
+	tests := []struct {
+		name       string
+		configured string
+		wantErr    bool
+		want       map[string]bool // UUID to orphaned
+	}{
+		{"configured location", fixturePath("games", "instances"), false,
+			map[string]bool{"a": false, "b": true, "c": true, "d": true}},
+		{"missing configured location", fixturePath("nowhere"), false,
+			map[string]bool{"a": true, "b": true, "c": true, "d": true}},
+		{"unset configured location", "", false,
+			map[string]bool{"a": true, "b": true, "c": true, "d": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFixture(t, files, Options{InstanceDirs: []string{fixturePath("extra", "instances")}})
+			ftbApp.InstallLocation = fixturePath("ftba")
+			ftbApp.Settings.InstanceLocation = tt.configured
+
+			pIM, _, err := getInstances([]appLog{{path: fixturePath("ftba", "logs", "latest.log")}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v", err)
+			}
+			got := make(map[string]bool)
+			for uuid, i := range pIM {
+				got[uuid] = i.Orphaned
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Nothing found anywhere reports why the configured location was unusable
+	useFixture(t, fstest.MapFS{}, Options{})
+	ftbApp.Settings.InstanceLocation = fixturePath("nowhere")
+	if _, _, err := getInstances(nil); err == nil {
+		t.Error("expected the configured location error when no instances are found")
+	}
+}
+
+func TestInstanceLocationsFromLogs(t *testing.T) {
+	log := "[12:00:00] [main/INFO] Loading /home/me/FTBA/instances\n" +
+		"2024-01-02 10:00:00 [INFO] instanceLocation=\"C:\\Users\\Jo Doe\\FTBA\\instances\"\n" +
+		"/opt/ftba/instances/pack/mods\n" +
+		"[main/INFO] no path in /var/log/ftba.log\n"
+	useFixture(t, fstest.MapFS{
+		"ftba/logs/latest.log":          {Data: []byte(log)},
+		"ftba/logs/2024-01-01-1.log.gz": {Data: gzipped(t, "Moved to '/mnt/games/instances'\n")},
+	}, Options{})
+	logs := []appLog{
+		{path: fixturePath("ftba", "logs", "latest.log")},
+		{path: fixturePath("ftba", "logs", "2024-01-01-1.log.gz")},
+		{path: fixturePath("ftba", "logs", "missing.log")},
+	}
+
+	want := []string{"/home/me/FTBA/instances", `C:\Users\Jo Doe\FTBA\instances`, "/opt/ftba/instances", "/mnt/games/instances"}
+	if got := instanceLocationsFromLogs(logs); !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
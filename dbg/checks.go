@@ -0,0 +1,96 @@
+package dbg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const defaultCheckTimeout = 30 * time.Second
+
+// loadNetworkChecks reads the check definitions from source, which may be a
+// local file or a http(s) URL. An empty source uses the embedded checks.json.
+// Checks that don't apply to the running OS/arch are filtered out.
+func loadNetworkChecks(source string) ([]CheckURLStruct, error) {
+	data := defaultNetworkChecks
+	if source != "" {
+		var err error
+		if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+			data, err = fetchCheckDefinitions(source)
+		} else {
+			data, err = os.ReadFile(source)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var defs CheckDefinitions
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, fmt.Errorf("invalid network check definitions: %w", err)
+	}
+	if len(defs.Checks) == 0 {
+		return nil, errors.New("no network checks defined")
+	}
+
+	var checks []CheckURLStruct
+	for _, c := range defs.Checks {
+		if c.URL == "" {
+			return nil, errors.New("network check is missing a url")
+		}
+		if !c.appliesTo(platform.GOOS, platform.GOARCH) {
+			continue
+		}
+		if c.Method == "" {
+			c.Method = http.MethodGet
+		}
+		if len(c.ExpectedStatusCodes) == 0 {
+			c.ExpectedStatusCodes = []int{http.StatusOK}
+		}
+		if c.Severity == "" {
+			c.Severity = "error"
+		}
+		checks = append(checks, c)
+	}
+	return checks, nil
+}
+
+func fetchCheckDefinitions(url string) ([]byte, error) {
+	client := &http.Client{Timeout: defaultCheckTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch network checks from %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (c CheckURLStruct) appliesTo(goos, goarch string) bool {
+	if len(c.OS) > 0 && !slices.Contains(c.OS, goos) {
+		return false
+	}
+	if len(c.Arch) > 0 && !slices.Contains(c.Arch, goarch) {
+		return false
+	}
+	return true
+}
+
+func (c CheckURLStruct) timeout() time.Duration {
+	if c.Timeout == "" {
+		return defaultCheckTimeout
+	}
+	d, err := time.ParseDuration(c.Timeout)
+	if err != nil || d <= 0 {
+		return defaultCheckTimeout
+	}
+	return d
+}
@@ -0,0 +1,161 @@
+package dbg
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+const (
+	ntpTimeout = 5 * time.Second
+	// Queried when no other time source answered and -ntp was not given
+	defaultNTPServer = "pool.ntp.org"
+	// Hosts probed for a Date header when every network check failed
+	maxDateProbes = 3
+	// Seconds between the NTP epoch (1900) and the unix epoch (1970)
+	ntpEpochOffset = 2208988800
+)
+
+// headerClockSkew returns the median difference between the local clock and
+// the Date headers of the network check responses. Positive means the local
+// clock is ahead.
+func headerClockSkew(nc []NetworkCheck) (time.Duration, bool) {
+	var skews []time.Duration
+	for _, n := range nc {
+		if n.hasDate {
+			skews = append(skews, n.clockSkew)
+		}
+	}
+	if len(skews) == 0 {
+		return 0, false
+	}
+	return medianSkew(skews), true
+}
+
+func medianSkew(skews []time.Duration) time.Duration {
+	slices.Sort(skews)
+	return skews[len(skews)/2]
+}
+
+// unverifiedDateSkew reads the Date header from the hosts of the network
+// checks without verifying their certificates. A clock that is far enough
+// off fails every TLS handshake, so this is the only way to get a header in
+// the case that matters most. Nothing but the header is used.
+func unverifiedDateSkew(nc []NetworkCheck) (time.Duration, bool) {
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	var probed []string
+	var skews []time.Duration
+	for _, n := range nc {
+		u, err := url.Parse(n.URL)
+		if err != nil || u.Host == "" || slices.Contains(probed, u.Host) {
+			continue
+		}
+		probed = append(probed, u.Host)
+		resp, err := client.Head(u.Scheme + "://" + u.Host + "/")
+		if err != nil {
+			pterm.Debug.Printfln("Unable to read the time from %s: %s", u.Host, err)
+			continue
+		}
+		resp.Body.Close()
+		if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+			skews = append(skews, time.Since(date))
+		}
+		if len(skews) == maxDateProbes {
+			break
+		}
+	}
+	if len(skews) == 0 {
+		return 0, false
+	}
+	return medianSkew(skews), true
+}
+
+// ntpClockSkew queries server using SNTP and returns how far the local clock
+// is ahead of it
+func ntpClockSkew(server string) (time.Duration, error) {
+	conn, err := net.DialTimeout("udp", net.JoinHostPort(server, "123"), ntpTimeout)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(ntpTimeout)); err != nil {
+		return 0, err
+	}
+
+	req := make([]byte, 48)
+	// LI 0, version 4, mode 3 (client)
+	req[0] = 0x23
+	sent := time.Now()
+	if _, err := conn.Write(req); err != nil {
+		return 0, err
+	}
+	resp := make([]byte, 48)
+	n, err := conn.Read(resp)
+	if err != nil {
+		return 0, err
+	}
+	received := time.Now()
+	if n < 48 {
+		return 0, errors.New("short NTP response")
+	}
+
+	secs := binary.BigEndian.Uint32(resp[40:44])
+	frac := binary.BigEndian.Uint32(resp[44:48])
+	if secs == 0 {
+		return 0, errors.New("NTP server returned no time")
+	}
+	serverTime := time.Unix(int64(secs)-ntpEpochOffset, int64(float64(frac)/(1<<32)*1e9))
+	// Assume the response spent half the round trip in flight
+	serverTime = serverTime.Add(received.Sub(sent) / 2)
+	return received.Sub(serverTime), nil
+}
+
+// measureClockSkew prefers NTP when a server is given and falls back to the
+// HTTP Date headers of the checks, then headers read without certificate
+// verification and finally the default NTP server
+func measureClockSkew(nc []NetworkCheck, ntpServer string) (time.Duration, string, bool) {
+	if ntpServer != "" {
+		skew, err := ntpClockSkew(ntpServer)
+		if err == nil {
+			return skew, "ntp " + ntpServer, true
+		}
+		pterm.Warning.Println("NTP query failed, falling back to HTTP Date headers:", err)
+	}
+	if skew, ok := headerClockSkew(nc); ok {
+		return skew, "http date headers", true
+	}
+	if skew, ok := unverifiedDateSkew(nc); ok {
+		return skew, "http date headers (certificate not verified)", true
+	}
+	if ntpServer == "" {
+		skew, err := ntpClockSkew(defaultNTPServer)
+		if err == nil {
+			return skew, "ntp " + defaultNTPServer, true
+		}
+		pterm.Debug.Println("NTP query failed:", err)
+	}
+	return 0, "", false
+}
+
+func clockSkewMessage(skew time.Duration) string {
+	direction := "ahead"
+	if skew < 0 {
+		direction = "behind"
+		skew = -skew
+	}
+	return fmt.Sprintf("The system clock is %s %s, this causes certificate and Microsoft login errors. Enable automatic time sync", skew.Round(time.Second), direction)
+}
@@ -0,0 +1,32 @@
+package dbg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// A clock far enough off fails every TLS handshake, the skew still has to be
+// read from a server whose certificate can't be verified
+func TestUnverifiedDateSkew(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(2*time.Hour).UTC().Format(http.TimeFormat))
+	}))
+	defer srv.Close()
+
+	nc := runChecks([]CheckURLStruct{{URL: srv.URL, Method: http.MethodGet, ExpectedStatusCodes: []int{http.StatusOK}}}, nil)
+	if nc[0].Success {
+		t.Fatal("check against an untrusted certificate passed")
+	}
+	if _, ok := headerClockSkew(nc); ok {
+		t.Fatal("failed check returned a Date header")
+	}
+	skew, source, ok := measureClockSkew(nc, "")
+	if !ok {
+		t.Fatal("no clock skew measured")
+	}
+	if diff := skew + 2*time.Hour; diff < -5*time.Second || diff > 5*time.Second {
+		t.Errorf("skew %s from %s, want about -2h", skew, source)
+	}
+}
@@ -0,0 +1,336 @@
+package dbg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+const (
+	crashWindowBefore = 2 * time.Minute
+	crashWindowAfter  = 30 * time.Second
+)
+
+var (
+	// crash-2024-01-31_18.04.05-client.txt
+	crashFileTimeRe  = regexp.MustCompile(`crash-(\d{4}-\d{2}-\d{2}_\d{2}\.\d{2}\.\d{2})`)
+	crashTimeRe      = regexp.MustCompile(`(?m)^Time: (.+?)\r?$`)
+	crashTimeLayouts = []string{
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04:05.000",
+		"1/2/06, 3:04 PM",
+		"1/2/06 3:04 PM",
+		"02.01.06 15:04",
+		"02/01/06 15:04",
+		"2006/01/02 15:04",
+	}
+
+	// 2024-01-31 18:04:05 or 2024-01-31T18:04:05, used by the app logs
+	isoStampRe = regexp.MustCompile(`^\[?(\d{4}-\d{2}-\d{2})[ T](\d{2}:\d{2}:\d{2})`)
+	// [31Jan2024 18:04:05.123], used by debug.log
+	forgeStampRe = regexp.MustCompile(`^\[(\d{2}[A-Za-z]{3}\d{4}) (\d{2}:\d{2}:\d{2})`)
+	// [18:04:05], used by latest.log
+	clockStampRe = regexp.MustCompile(`^\[(\d{2}:\d{2}:\d{2})\]`)
+
+	crashExcerptLogs = []string{"latest.log", "debug.log"}
+)
+
+// crashTime reads when a crash report was written from its file name, falling
+// back to the Time: line inside the report
+func crashTime(name string, data []byte) (time.Time, bool) {
+	if m := crashFileTimeRe.FindStringSubmatch(name); m != nil {
+		if t, err := time.ParseInLocation("2006-01-02_15.04.05", m[1], time.Local); err == nil {
+			return t, true
+		}
+	}
+	if m := crashTimeRe.FindSubmatch(data); m != nil {
+		for _, layout := range crashTimeLayouts {
+			if t, err := time.ParseInLocation(layout, strings.TrimSpace(string(m[1])), time.Local); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// timedLog holds the lines of a log and when each was written. Lines without
+// a timestamp, such as stack traces, carry the time of the line above them.
+type timedLog struct {
+	lines  []string
+	stamps []time.Time
+}
+
+// keptLog is a log read during collection that the excerpts are cut from
+type keptLog struct {
+	data     []byte
+	modified time.Time
+}
+
+// appLog is an app log getAppLogs uploaded, crash excerpts are cut from it
+// once the instances have been scanned
+type appLog struct {
+	path     string
+	modified time.Time
+}
+
+// crashWindow is the time around one or more crashes that overlap
+type crashWindow struct {
+	from, to time.Time
+	crashes  []crashRef
+}
+
+type crashRef struct {
+	instance int
+	name     string
+}
+
+// lineStamp reads the full date and time at the start of a line
+func lineStamp(line string) (time.Time, bool) {
+	if m := isoStampRe.FindStringSubmatch(line); m != nil {
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", m[1]+" "+m[2], time.Local)
+		return t, err == nil
+	}
+	if m := forgeStampRe.FindStringSubmatch(line); m != nil {
+		t, err := time.ParseInLocation("02Jan2006 15:04:05", m[1]+" "+m[2], time.Local)
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
+
+// parseTimedLog timestamps every line of a log. latest.log only records the
+// time of day so its dates are worked out back from the file's modification
+// time, counting a day for every time the clock goes backwards.
+func parseTimedLog(data []byte, modified time.Time) timedLog {
+	lines := strings.SplitAfter(string(data), "\n")
+	stamps := make([]time.Time, len(lines))
+	clocks := make([]time.Duration, len(lines))
+	hasClock := make([]bool, len(lines))
+
+	var rollovers int
+	var lastClock time.Duration
+	var seenClock bool
+	for i, line := range lines {
+		if t, ok := lineStamp(line); ok {
+			stamps[i] = t
+			continue
+		}
+		if m := clockStampRe.FindStringSubmatch(line); m != nil {
+			t, err := time.Parse("15:04:05", m[1])
+			if err != nil {
+				continue
+			}
+			clock := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+			if seenClock && clock < lastClock {
+				rollovers++
+			}
+			clocks[i], hasClock[i], lastClock, seenClock = clock, true, clock, true
+		}
+	}
+	if seenClock {
+		y, mo, d := modified.In(time.Local).Date()
+		day := time.Date(y, mo, d, 0, 0, 0, 0, time.Local).AddDate(0, 0, -rollovers)
+		lastClock, seenClock = 0, false
+		for i := range lines {
+			if !hasClock[i] {
+				continue
+			}
+			if seenClock && clocks[i] < lastClock {
+				day = day.AddDate(0, 0, 1)
+			}
+			stamps[i] = day.Add(clocks[i])
+			lastClock, seenClock = clocks[i], true
+		}
+	}
+
+	var current time.Time
+	for i := range stamps {
+		if stamps[i].IsZero() {
+			stamps[i] = current
+		}
+		current = stamps[i]
+	}
+	return timedLog{lines: lines, stamps: stamps}
+}
+
+// excerpt returns the lines written between from and to
+func (l timedLog) excerpt(from, to time.Time) []byte {
+	var excerpt strings.Builder
+	for i, line := range l.lines {
+		if inWindow(l.stamps[i], from, to) {
+			excerpt.WriteString(line)
+		}
+	}
+	return []byte(excerpt.String())
+}
+
+func inWindow(t, from, to time.Time) bool {
+	return !t.IsZero() && !t.Before(from) && !t.After(to)
+}
+
+// crashTimes finds when each crash report in reports was written
+func crashTimes(reports map[string]keptLog) map[string]time.Time {
+	times := make(map[string]time.Time)
+	for name, report := range reports {
+		if at, ok := crashTime(name, report.data); ok {
+			times[name] = at
+		} else {
+			pterm.Debug.Println("Unable to find the time of", name)
+		}
+	}
+	return times
+}
+
+// crashExcerpts uploads the part of each instance log in logs written around
+// the crashes in times. Each log is timestamped once for all its crashes.
+func crashExcerpts(logs map[string]keptLog, times map[string]time.Time) map[string][]CrashExcerpt {
+	excerpts := make(map[string][]CrashExcerpt)
+	crashes := slices.Sorted(maps.Keys(times))
+	for _, name := range crashExcerptLogs {
+		log, ok := logs[name]
+		if !ok {
+			continue
+		}
+		var parsed *timedLog
+		for _, crash := range crashes {
+			from, to := times[crash].Add(-crashWindowBefore), times[crash].Add(crashWindowAfter)
+			// A log last written before the window can't contain it
+			if log.modified.Before(from) {
+				continue
+			}
+			if parsed == nil {
+				l := parseTimedLog(log.data, log.modified)
+				parsed = &l
+			}
+			if ref, ok := uploadExcerpt(name, from, to, []string{crash}, parsed.excerpt(from, to)); ok {
+				excerpts[crash] = append(excerpts[crash], CrashExcerpt{Log: name, Ref: ref, From: from.Unix(), To: to.Unix()})
+			}
+		}
+	}
+	return excerpts
+}
+
+// appLogExcerpts adds the app log lines written around every crash in
+// instanceLogs to their excerpts. Only the app logs getAppLogs uploaded are
+// used, each is read once and crashes whose windows overlap, such as two
+// instances crashing together, share one excerpt.
+func appLogExcerpts(instanceLogs []InstanceLogs, appLogs []appLog) {
+	windows := crashWindows(instanceLogs)
+	if len(windows) == 0 {
+		return
+	}
+	for _, log := range appLogs {
+		// A log last written before the first window can't contain any
+		if !log.modified.IsZero() && log.modified.Before(windows[0].from) {
+			continue
+		}
+		name := filepath.Base(log.path)
+		excerpts, err := readAppLogWindows(log.path, windows)
+		if err != nil {
+			pterm.Debug.Printfln("Unable to read %s for crash excerpts: %s", name, err)
+		}
+		label := "app/" + name
+		for i, w := range windows {
+			var crashNames []string
+			for _, c := range w.crashes {
+				crashNames = append(crashNames, c.name)
+			}
+			ref, ok := uploadExcerpt(label, w.from, w.to, crashNames, excerpts[i])
+			if !ok {
+				continue
+			}
+			for _, c := range w.crashes {
+				l := &instanceLogs[c.instance]
+				if l.CrashExcerpts == nil {
+					l.CrashExcerpts = make(map[string][]CrashExcerpt)
+				}
+				l.CrashExcerpts[c.name] = append(l.CrashExcerpts[c.name], CrashExcerpt{Log: label, Ref: ref, From: w.from.Unix(), To: w.to.Unix()})
+			}
+		}
+	}
+}
+
+// crashWindows lists the time around every crash in order, merging windows
+// that overlap
+func crashWindows(instanceLogs []InstanceLogs) []crashWindow {
+	var windows []crashWindow
+	for i, l := range instanceLogs {
+		for name, at := range l.crashTimes {
+			windows = append(windows, crashWindow{
+				from:    at.Add(-crashWindowBefore),
+				to:      at.Add(crashWindowAfter),
+				crashes: []crashRef{{instance: i, name: name}},
+			})
+		}
+	}
+	slices.SortFunc(windows, func(a, b crashWindow) int {
+		return a.from.Compare(b.from)
+	})
+	var merged []crashWindow
+	for _, w := range windows {
+		if n := len(merged); n > 0 && !w.from.After(merged[n-1].to) {
+			last := &merged[n-1]
+			if w.to.After(last.to) {
+				last.to = w.to
+			}
+			last.crashes = append(last.crashes, w.crashes...)
+			continue
+		}
+		merged = append(merged, w)
+	}
+	return merged
+}
+
+// readAppLogWindows streams an app log, decompressing .gz logs, and returns
+// the lines written in each window. App logs carry full dates so nothing but
+// the excerpts is kept in memory.
+func readAppLogWindows(path string, windows []crashWindow) ([][]byte, error) {
+	excerpts := make([][]byte, len(windows))
+	r, err := openLog(path)
+	if err != nil {
+		return excerpts, err
+	}
+	defer r.Close()
+
+	br := bufio.NewReader(r)
+	var current time.Time
+	for {
+		line, err := br.ReadString('\n')
+		if t, ok := lineStamp(line); ok {
+			current = t
+		}
+		for i, w := range windows {
+			if inWindow(current, w.from, w.to) {
+				excerpts[i] = append(excerpts[i], line...)
+			}
+		}
+		if err == io.EOF {
+			return excerpts, nil
+		}
+		if err != nil {
+			return excerpts, err
+		}
+	}
+}
+
+// uploadExcerpt uploads the lines of log cut out around crashes
+func uploadExcerpt(log string, from, to time.Time, crashes []string, excerpt []byte) (string, bool) {
+	if len(excerpt) == 0 {
+		return "", false
+	}
+	around := strings.Join(crashes, ", ")
+	header := fmt.Sprintf("%s from %s to %s, around %s\n\n", log, from.Format(time.DateTime), to.Format(time.DateTime), around)
+	ref, err := uploads.addImportant(fmt.Sprintf("%s around %s", log, around), append([]byte(header), excerpt...), "log")
+	if err != nil {
+		pterm.Error.Println("Error uploading crash excerpt:", err)
+		return "", false
+	}
+	return ref, true
+}
@@ -0,0 +1,152 @@
+package dbg
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func at(s string) time.Time {
+	t, err := time.ParseInLocation(time.DateTime, s, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestTimedLogExcerpt(t *testing.T) {
+	tests := []struct {
+		name     string
+		log      string
+		modified string
+		crash    string
+		want     string
+	}{
+		{
+			"midnight rollover",
+			"[23:57:00] [main/INFO]: too early\n" +
+				"[23:59:30] [main/INFO]: before midnight\n" +
+				"java.lang.RuntimeException: boom\n" +
+				"\tat Foo.bar(Foo.java:1)\n" +
+				"[00:00:10] [main/INFO]: after midnight\n" +
+				"[00:01:00] [main/INFO]: too late\n",
+			"2024-01-02 00:05:00", "2024-01-02 00:00:05",
+			"[23:59:30] [main/INFO]: before midnight\n" +
+				"java.lang.RuntimeException: boom\n" +
+				"\tat Foo.bar(Foo.java:1)\n" +
+				"[00:00:10] [main/INFO]: after midnight\n",
+		},
+		{
+			"several days in one log",
+			"[10:00:00] [main/INFO]: day one\n" +
+				"[09:00:00] [main/INFO]: day two\n" +
+				"[10:00:00] [main/INFO]: day two crash\n" +
+				"[08:00:00] [main/INFO]: day three\n",
+			"2024-01-03 08:00:00", "2024-01-02 10:00:00",
+			"[10:00:00] [main/INFO]: day two crash\n",
+		},
+		{
+			"log kept being written after the crash",
+			"[09:59:00] [main/INFO]: loading\n" +
+				"[10:00:20] [main/FATAL]: crashed\n" +
+				"[11:00:00] [main/INFO]: relaunched\n",
+			"2024-01-02 11:00:00", "2024-01-02 10:00:00",
+			"[09:59:00] [main/INFO]: loading\n" +
+				"[10:00:20] [main/FATAL]: crashed\n",
+		},
+		{
+			"log rewritten by a later session",
+			"[10:00:00] [main/INFO]: next day\n",
+			"2024-01-03 10:05:00", "2024-01-02 10:00:00",
+			"",
+		},
+		{
+			"debug.log dates",
+			"[02Jan2024 09:57:00.000] [main/INFO]: too early\n" +
+				"[02Jan2024 09:59:00.000] [main/INFO]: in window\n" +
+				"[02Jan2024 10:01:00.000] [main/INFO]: too late\n",
+			"2024-01-02 10:01:00", "2024-01-02 10:00:00",
+			"[02Jan2024 09:59:00.000] [main/INFO]: in window\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crash := at(tt.crash)
+			l := parseTimedLog([]byte(tt.log), at(tt.modified))
+			if got := string(l.excerpt(crash.Add(-crashWindowBefore), crash.Add(crashWindowAfter))); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrashTime(t *testing.T) {
+	tests := []struct {
+		name, report string
+		want         string
+	}{
+		{"crash-2024-01-02_10.00.05-client.txt", "", "2024-01-02 10:00:05"},
+		{"crash.txt", "---- Minecraft Crash Report ----\nTime: 2024-01-02 10:00:05\n", "2024-01-02 10:00:05"},
+		{"crash.txt", "Time: 1/2/24, 10:00 AM\r\n", "2024-01-02 10:00:00"},
+	}
+	for _, tt := range tests {
+		got, ok := crashTime(tt.name, []byte(tt.report))
+		if !ok || !got.Equal(at(tt.want)) {
+			t.Errorf("crashTime(%q) = %s, %t, want %s", tt.name, got, ok, tt.want)
+		}
+	}
+	if _, ok := crashTime("crash.txt", []byte("no time here")); ok {
+		t.Error("found a time in a report without one")
+	}
+}
+
+// Instances that crash together share one excerpt of each app log
+func TestAppLogExcerpts(t *testing.T) {
+	appLogData := "2024-01-02 09:50:00 [INFO] too early\n" +
+		"2024-01-02 09:59:00 [INFO] launching\n" +
+		"2024-01-02 10:00:10 [ERROR] instance exited\n" +
+		"  with code 1\n" +
+		"2024-01-02 12:00:00 [INFO] too late\n"
+	useFixture(t, fstest.MapFS{
+		"ftba/logs/latest.log":          {Data: []byte(appLogData), ModTime: at("2024-01-02 12:00:00")},
+		"ftba/logs/2024-01-02-1.log.gz": {Data: gzipped(t, appLogData), ModTime: at("2024-01-02 12:00:00")},
+		"ftba/logs/2023-12-01-1.log.gz": {Data: gzipped(t, "2023-12-01 10:00:00 [INFO] old\n"), ModTime: at("2023-12-01 10:00:00")},
+	}, Options{})
+	ftbApp.InstallLocation = fixturePath("ftba")
+
+	instanceLogs := []InstanceLogs{
+		{Name: "A", crashTimes: map[string]time.Time{"crash-a.txt": at("2024-01-02 10:00:00")}},
+		{Name: "B", crashTimes: map[string]time.Time{"crash-b.txt": at("2024-01-02 10:00:20")}},
+		{Name: "C"},
+	}
+	var appLogs []appLog
+	for _, name := range []string{"latest.log", "2024-01-02-1.log.gz", "2023-12-01-1.log.gz"} {
+		info, err := platform.FS.Stat(fixturePath("ftba", "logs", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		appLogs = append(appLogs, appLog{path: fixturePath("ftba", "logs", name), modified: info.ModTime()})
+	}
+	appLogExcerpts(instanceLogs, appLogs)
+
+	a, b := instanceLogs[0].CrashExcerpts["crash-a.txt"], instanceLogs[1].CrashExcerpts["crash-b.txt"]
+	if len(a) != 2 || len(b) != 2 {
+		t.Fatalf("got excerpts %v and %v, want one per recent app log", a, b)
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Errorf("crashes in the same window got different excerpts: %v and %v", a[i], b[i])
+		}
+		got := uploaded(t, a[i].Ref)
+		if !strings.Contains(got, "launching\n") || !strings.Contains(got, "  with code 1\n") || strings.Contains(got, "too") {
+			t.Errorf("%s excerpt = %q", a[i].Log, got)
+		}
+	}
+	if len(uploads.inline) != 2 {
+		t.Errorf("uploaded %d excerpts, want 2", len(uploads.inline))
+	}
+	if instanceLogs[2].CrashExcerpts != nil {
+		t.Error("excerpts added to an instance without crashes")
+	}
+}
@@ -0,0 +1,247 @@
+package dbg
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pterm/pterm"
+)
+
+type diffSection struct {
+	Title   string
+	Changes []string
+}
+
+// RunDiff compares two manifests, each given as a dbg code or a local file,
+// to show what changed between two runs
+func RunDiff(opts Options, before, after string) {
+	options = opts
+	if before == "" || after == "" {
+		pterm.Error.Println("Usage: diff <before> <after>, each a dbg:code or manifest.json")
+		return
+	}
+	a, _, err := loadManifest(before)
+	if err != nil {
+		pterm.Error.Printfln("Failed to load %s: %s", before, err)
+		return
+	}
+	b, _, err := loadManifest(after)
+	if err != nil {
+		pterm.Error.Printfln("Failed to load %s: %s", after, err)
+		return
+	}
+
+	pterm.DefaultHeader.Printfln("Changes from %s to %s", before, after)
+	sections := diffManifests(a, b)
+	if len(sections) == 0 {
+		pterm.Info.Println("No differences found")
+		return
+	}
+	for _, s := range sections {
+		pterm.DefaultSection.Println(s.Title)
+		for _, c := range s.Changes {
+			pterm.Println(c)
+		}
+	}
+}
+
+func diffManifests(a, b Manifest) []diffSection {
+	var sections []diffSection
+	add := func(title string, changes []string) {
+		if len(changes) > 0 {
+			sections = append(sections, diffSection{title, changes})
+		}
+	}
+
+	var app []string
+	am, bm := a.AppDetails.Meta, b.AppDetails.Meta
+	app = diffValue(app, "App version", am.AppVersion, bm.AppVersion)
+	app = diffValue(app, "Branch", am.Branch, bm.Branch)
+	app = diffValue(app, "Commit", am.Commit, bm.Commit)
+	if a.System != nil && b.System != nil {
+		app = diffValue(app, "OS", a.System.OS, b.System.OS)
+	}
+	add("App", app)
+
+	add("Settings", diffSettings(a.SettingsDiff, b.SettingsDiff))
+	add("Instances", diffInstances(a.ProviderInstanceMapping, b.ProviderInstanceMapping))
+	add("Network checks", diffNetworkChecks(a.NetworkChecks, b.NetworkChecks))
+	add("New crash reports", newCrashReports(a.InstanceLogs, b))
+	add("Recommendations", diffStrings(a.Recommendations, b.Recommendations))
+	return sections
+}
+
+func diffValue(changes []string, name string, a, b any) []string {
+	if a == b {
+		return changes
+	}
+	return append(changes, fmt.Sprintf("~ %s: %v -> %v", name, a, b))
+}
+
+// diffSettings compares the non default settings recorded in each manifest,
+// a setting missing from one side is at its default there
+func diffSettings(a, b []SettingDiff) []string {
+	before := make(map[string]SettingDiff)
+	for _, s := range a {
+		before[s.Key] = s
+	}
+	after := make(map[string]SettingDiff)
+	for _, s := range b {
+		after[s.Key] = s
+	}
+
+	var changes []string
+	for _, key := range slices.Sorted(maps.Keys(after)) {
+		s := after[key]
+		old, ok := before[key]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("~ %s: %s -> %s", key, defaultLabel(s.Default), s.Value))
+		case old.Value != s.Value:
+			changes = append(changes, fmt.Sprintf("~ %s: %s -> %s", key, old.Value, s.Value))
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(before)) {
+		if _, ok := after[key]; !ok {
+			s := before[key]
+			changes = append(changes, fmt.Sprintf("~ %s: %s -> %s", key, s.Value, defaultLabel(s.Default)))
+		}
+	}
+	return changes
+}
+
+func defaultLabel(value string) string {
+	if value == "" {
+		return "(default)"
+	}
+	return value + " (default)"
+}
+
+func diffInstances(a, b map[string]Instances) []string {
+	before := instancesByUUID(a)
+	after := instancesByUUID(b)
+
+	var changes []string
+	for _, uuid := range slices.Sorted(maps.Keys(after)) {
+		i := after[uuid]
+		old, ok := before[uuid]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("+ %s (%s %s)", i.Name, i.McVersion, i.ModLoader))
+			continue
+		}
+		var c []string
+		c = diffValue(c, "pack version", old.Version, i.Version)
+		c = diffValue(c, "Minecraft", old.McVersion, i.McVersion)
+		c = diffValue(c, "loader", old.ModLoader, i.ModLoader)
+		c = diffValue(c, "memory", old.Memory, i.Memory)
+		c = diffValue(c, "JVM args", old.JvmArgs, i.JvmArgs)
+		c = diffValue(c, "Java", old.JrePath, i.JrePath)
+		c = diffValue(c, "installed", old.InstallComplete, i.InstallComplete)
+		for _, line := range c {
+			changes = append(changes, fmt.Sprintf("~ %s %s", i.Name, strings.TrimPrefix(line, "~ ")))
+		}
+	}
+	for _, uuid := range slices.Sorted(maps.Keys(before)) {
+		if _, ok := after[uuid]; !ok {
+			i := before[uuid]
+			changes = append(changes, fmt.Sprintf("- %s (%s %s)", i.Name, i.McVersion, i.ModLoader))
+		}
+	}
+	return changes
+}
+
+func instancesByUUID(m map[string]Instances) map[string]Instances {
+	byUUID := make(map[string]Instances, len(m))
+	for key, i := range m {
+		if i.UUID == "" {
+			i.UUID = key
+		}
+		byUUID[i.UUID] = i
+	}
+	return byUUID
+}
+
+// uuidRe matches the RANDOM_UUID runChecks substitutes into check URLs
+var uuidRe = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+
+// checkKey identifies a check across runs by its URL before the random
+// request id was filled in
+func checkKey(url string) string {
+	return uuidRe.ReplaceAllString(url, "RANDOM_UUID")
+}
+
+func diffNetworkChecks(a, b []NetworkCheck) []string {
+	before := make(map[string]NetworkCheck)
+	for _, n := range a {
+		before[checkKey(n.URL)] = n
+	}
+	var changes []string
+	seen := make(map[string]bool)
+	for _, n := range b {
+		key := checkKey(n.URL)
+		seen[key] = true
+		old, ok := before[key]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("+ %s: %s", key, checkOutcome(n)))
+		case old.Success != n.Success:
+			changes = append(changes, fmt.Sprintf("~ %s: %s -> %s", key, checkOutcome(old), checkOutcome(n)))
+		}
+	}
+	for _, n := range a {
+		if key := checkKey(n.URL); !seen[key] {
+			changes = append(changes, fmt.Sprintf("- %s", key))
+		}
+	}
+	return changes
+}
+
+func checkOutcome(n NetworkCheck) string {
+	if n.Success {
+		return "passed"
+	}
+	return "failed"
+}
+
+func newCrashReports(a []InstanceLogs, b Manifest) []string {
+	before := make(map[string]bool)
+	for _, l := range a {
+		for name := range l.CrashLogs {
+			before[l.UUID+"/"+name] = true
+		}
+	}
+	var changes []string
+	for _, l := range b.InstanceLogs {
+		for _, name := range slices.Sorted(maps.Keys(l.CrashLogs)) {
+			if !before[l.UUID+"/"+name] {
+				line := fmt.Sprintf("+ %s: %s", l.Name, name)
+				for _, s := range []string{b.publicLink(l.CrashLogs[name]), b.refLocation(l.CrashLogs[name])} {
+					if s != "" {
+						line += " " + s
+					}
+				}
+				changes = append(changes, line)
+			}
+		}
+	}
+	return changes
+}
+
+// diffStrings lists entries only in b as added and only in a as removed
+func diffStrings(a, b []string) []string {
+	var changes []string
+	for _, s := range b {
+		if !slices.Contains(a, s) {
+			changes = append(changes, "+ "+s)
+		}
+	}
+	for _, s := range a {
+		if !slices.Contains(b, s) {
+			changes = append(changes, "- "+s)
+		}
+	}
+	return changes
+}
@@ -0,0 +1,28 @@
+package dbg
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffNetworkChecks(t *testing.T) {
+	license := "https://api.minecraftservices.com/entitlements/license?requestId="
+	a := []NetworkCheck{
+		{URL: license + "0b6f4c1e-8f0a-4b8e-9d55-2f3c1a7e9b10", Success: true},
+		{URL: "https://api.feed-the-beast.com", Success: true},
+		{URL: "https://removed.example.com", Success: true},
+	}
+	b := []NetworkCheck{
+		{URL: license + "5d2e9a47-1c3b-4f6d-8e0a-7b9c2d4f6a81", Success: true},
+		{URL: "https://api.feed-the-beast.com", Success: false},
+		{URL: "https://added.example.com", Success: true},
+	}
+	want := []string{
+		"~ https://api.feed-the-beast.com: passed -> failed",
+		"+ https://added.example.com: passed",
+		"- https://removed.example.com",
+	}
+	if got := diffNetworkChecks(a, b); !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,171 @@
+package dbg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+const dnsTimeout = 5 * time.Second
+
+var (
+	defaultDNSResolvers = []string{"1.1.1.1", "8.8.8.8", "9.9.9.9"}
+	hostsFileKeywords   = []string{"feed-the-beast", "ftb", "creeperhost", "mojang", "minecraft", "microsoft", "xboxlive", "live.com"}
+)
+
+// checkHosts returns the unique host names used by the network checks
+func checkHosts(checks []CheckURLStruct) []string {
+	var hosts []string
+	for _, c := range checks {
+		u, err := url.Parse(c.URL)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		if !slices.Contains(hosts, u.Hostname()) {
+			hosts = append(hosts, u.Hostname())
+		}
+	}
+	return hosts
+}
+
+func runDNSChecks(hosts []string, resolvers []string) []DNSCheck {
+	if len(resolvers) == 0 {
+		resolvers = defaultDNSResolvers
+	}
+	results := make([]DNSCheck, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = checkHostDNS(host, resolvers)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func checkHostDNS(host string, resolvers []string) DNSCheck {
+	check := DNSCheck{
+		Host:   host,
+		System: resolveHost(net.DefaultResolver, host),
+		Public: make(map[string]DNSAnswer),
+	}
+	var publicAddrs []string
+	for _, r := range resolvers {
+		answer := resolveHost(publicResolver(r), host)
+		check.Public[r] = answer
+		publicAddrs = append(publicAddrs, answer.Addresses...)
+	}
+
+	if check.System.NXDomain {
+		if len(publicAddrs) > 0 {
+			check.Problems = append(check.Problems, "NXDOMAIN from the system resolver but public resolvers return addresses, the domain may be filtered")
+		} else {
+			check.Problems = append(check.Problems, "NXDOMAIN from all resolvers")
+		}
+	} else if check.System.Error != "" {
+		check.Problems = append(check.Problems, fmt.Sprintf("system resolver failed: %s", check.System.Error))
+	}
+
+	for _, addr := range check.System.Addresses {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			continue
+		}
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() {
+			check.Problems = append(check.Problems, fmt.Sprintf("resolves to local address %s, this is usually hosts file or DNS level blocking", addr))
+		}
+	}
+
+	if len(check.System.Addresses) > 0 && len(publicAddrs) > 0 && !slices.ContainsFunc(check.System.Addresses, func(a string) bool {
+		return slices.Contains(publicAddrs, a)
+	}) {
+		// CDNs hand out different addresses per resolver, so this is only a hint
+		check.Problems = append(check.Problems, fmt.Sprintf("system resolver returned %s which doesn't match any public resolver answer", strings.Join(check.System.Addresses, ", ")))
+	}
+	return check
+}
+
+func publicResolver(server string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			d := net.Dialer{Timeout: dnsTimeout}
+			return d.DialContext(ctx, network, net.JoinHostPort(server, "53"))
+		},
+	}
+}
+
+// resolveHost looks up the A and AAAA records of host separately
+func resolveHost(resolver *net.Resolver, host string) DNSAnswer {
+	var answer DNSAnswer
+	notFound := 0
+	for _, family := range []string{"ip4", "ip6"} {
+		ctx, cancel := context.WithTimeout(context.Background(), dnsTimeout)
+		ips, err := resolver.LookupIP(ctx, family, host)
+		cancel()
+		if err != nil {
+			var dnsErr *net.DNSError
+			if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+				notFound++
+				continue
+			}
+			// Missing AAAA records are common, only report A lookup failures
+			if family == "ip4" {
+				answer.Error = err.Error()
+			}
+			continue
+		}
+		for _, ip := range ips {
+			answer.Addresses = append(answer.Addresses, ip.String())
+		}
+	}
+	answer.NXDomain = notFound == 2
+	return answer
+}
+
+// getHostsFileEntries returns the lines in the hosts file that mention
+// FTB, Mojang or Microsoft domains
+func getHostsFileEntries() ([]string, error) {
+	data, err := platform.FS.ReadFile(platform.HostsFile)
+	if err != nil {
+		return nil, err
+	}
+	var entries []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lower := strings.ToLower(line)
+		for _, keyword := range hostsFileKeywords {
+			if strings.Contains(lower, keyword) {
+				entries = append(entries, line)
+				break
+			}
+		}
+	}
+	return entries, scanner.Err()
+}
+
+func printDNSCheck(check DNSCheck) {
+	if len(check.Problems) == 0 {
+		pterm.Success.Printfln("%s: %s", check.Host, strings.Join(check.System.Addresses, ", "))
+		return
+	}
+	for _, problem := range check.Problems {
+		pterm.Warning.Printfln("%s: %s", check.Host, problem)
+	}
+}
@@ -0,0 +1,146 @@
+package dbg
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+type (
+	// FileSystem is the file access used by the discovery logic. Paths are
+	// native paths as built by filepath.Join, not slash separated fs.FS paths.
+	FileSystem interface {
+		ReadDir(name string) ([]fs.DirEntry, error)
+		ReadFile(name string) ([]byte, error)
+		Stat(name string) (fs.FileInfo, error)
+		Open(name string) (fs.File, error)
+	}
+
+	// Platform holds the OS specific roots the tool searches. Swapping it out
+	// allows Windows or macOS layouts to be simulated from any OS.
+	Platform struct {
+		GOOS            string
+		GOARCH          string
+		HomeDir         string
+		LocalAppData    string
+		WindowsAppPath  string
+		OverwolfAppPath string
+		OverwolfAppLogs string
+		MacAppPath      string
+		HostsFile       string
+		FS              FileSystem
+	}
+
+	osFS struct{}
+
+	// wrappedFS maps native paths onto an fs.FS
+	wrappedFS struct {
+		fsys fs.FS
+	}
+)
+
+var platform = defaultPlatform()
+
+func defaultPlatform() Platform {
+	home, _ := os.UserHomeDir()
+	return Platform{
+		GOOS:            runtime.GOOS,
+		GOARCH:          runtime.GOARCH,
+		HomeDir:         home,
+		LocalAppData:    os.Getenv("localappdata"),
+		WindowsAppPath:  windowsAppPath,
+		OverwolfAppPath: overwolfAppPath,
+		OverwolfAppLogs: overwolfAppLogs,
+		MacAppPath:      macAppPath,
+		HostsFile:       hostsFilePath,
+		FS:              osFS{},
+	}
+}
+
+// SetPlatform replaces the OS roots and file system used for discovery
+func SetPlatform(p Platform) {
+	if p.FS == nil {
+		p.FS = osFS{}
+	}
+	platform = p
+}
+
+// NewFS wraps an fs.FS (e.g. fstest.MapFS) so it can be used as a Platform
+// file system. Volume names and leading separators are stripped, so
+// "C:\Users\me\.ftba" and "/home/me/.ftba" resolve to "Users/me/.ftba" and
+// "home/me/.ftba" respectively.
+func NewFS(fsys fs.FS) FileSystem {
+	return wrappedFS{fsys: fsys}
+}
+
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (osFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (osFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+func (w wrappedFS) fsPath(name string) (string, error) {
+	name = strings.ReplaceAll(filepath.ToSlash(name), "\\", "/")
+	if len(name) >= 2 && name[1] == ':' {
+		name = name[2:]
+	}
+	name = path.Clean(strings.Trim(name, "/"))
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	return name, nil
+}
+
+func (w wrappedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := w.fsPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ReadDir(w.fsys, p)
+}
+
+func (w wrappedFS) ReadFile(name string) ([]byte, error) {
+	p, err := w.fsPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ReadFile(w.fsys, p)
+}
+
+func (w wrappedFS) Stat(name string) (fs.FileInfo, error) {
+	p, err := w.fsPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Stat(w.fsys, p)
+}
+
+func (w wrappedFS) Open(name string) (fs.File, error) {
+	p, err := w.fsPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return w.fsys.Open(p)
+}
+
+// pathExists is shared.DoesPathExist routed through the platform file system
+func pathExists(path string) bool {
+	if path == "" {
+		return false
+	}
+	_, err := platform.FS.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,69 @@
+package dbg
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/pterm/pterm"
+)
+
+// useFixture points discovery at files, a tree with native looking paths
+// such as "home/me/.ftba/logs/latest.log", and inlines every upload so the
+// tests never reach a paste server. Everything is restored afterwards.
+func useFixture(t *testing.T, files fstest.MapFS, opts Options) {
+	t.Helper()
+	oldPlatform, oldOptions, oldApp, oldUploads := platform, options, ftbApp, uploads
+	pterm.DisableOutput()
+	t.Cleanup(func() {
+		platform, options, ftbApp, uploads = oldPlatform, oldOptions, oldApp, oldUploads
+		pterm.EnableOutput()
+	})
+
+	p := defaultPlatform()
+	p.FS = NewFS(files)
+	SetPlatform(p)
+	if opts.InlineLimit == 0 {
+		opts.InlineLimit = 1 << 30
+	}
+	options = opts
+	ftbApp = FTBApp{}
+	uploads = uploadQueue{}
+}
+
+// fixturePath builds a native absolute path for a fixture file
+func fixturePath(parts ...string) string {
+	return filepath.Join(append([]string{string(filepath.Separator)}, parts...)...)
+}
+
+// uploaded returns the content behind a reference returned by uploads.add
+func uploaded(t *testing.T, ref string) string {
+	t.Helper()
+	kind, _, file, err := parseLogRef(ref)
+	if err != nil || kind != "inline" || file >= len(uploads.inline) {
+		t.Fatalf("reference %q is not an inline upload", ref)
+	}
+	data, err := uploads.inline[file].decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestNewFS(t *testing.T) {
+	fsys := NewFS(fstest.MapFS{"Users/me/.ftba/settings.json": {Data: []byte("{}")}})
+	for _, name := range []string{`C:\Users\me\.ftba\settings.json`, "/Users/me/.ftba/settings.json", "Users/me/.ftba/settings.json"} {
+		if _, err := fsys.ReadFile(name); err != nil {
+			t.Errorf("ReadFile(%q): %s", name, err)
+		}
+	}
+	f, err := fsys.Open(`C:\Users\me\.ftba\settings.json`)
+	if err != nil {
+		t.Errorf("Open: %s", err)
+	} else {
+		_ = f.Close()
+	}
+	if _, err := fsys.ReadFile("/Users/me/.ftba/missing.json"); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
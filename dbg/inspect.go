@@ -0,0 +1,222 @@
+package dbg
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pterm/pterm"
+)
+
+const (
+	inspectExit = "Exit"
+	inspectBack = "Back"
+	inspectApp  = "App logs"
+)
+
+// RunInspect loads a manifest from a dbg code or a local file, prints a
+// summary and lets support browse the uploaded logs
+func RunInspect(opts Options, target string) {
+	options = opts
+	if target == "" {
+		pterm.Error.Println("Usage: inspect <dbg:code | manifest.json>")
+		return
+	}
+
+	manifest, code, err := loadManifest(target)
+	if err != nil {
+		pterm.Error.Println("Failed to load manifest:", err)
+		return
+	}
+	printManifestSummary(manifest, code)
+	browseLogs(manifest)
+}
+
+// loadManifest reads target as a file if it exists, otherwise as a dbg code
+func loadManifest(target string) (Manifest, string, error) {
+	var manifest Manifest
+	var code string
+	var data []byte
+	var err error
+	if pathExists(target) {
+		data, err = platform.FS.ReadFile(target)
+	} else {
+		code = strings.TrimPrefix(strings.TrimSpace(target), "dbg:")
+		if code == "" {
+			return manifest, "", errors.New("no code given")
+		}
+		data, err = fetchPaste(code)
+	}
+	if err != nil {
+		return manifest, code, err
+	}
+	manifest, err = readManifest(data)
+	if err != nil {
+		return manifest, code, fmt.Errorf("not a debug manifest: %w", err)
+	}
+	return manifest, code, nil
+}
+
+func printManifestSummary(m Manifest, code string) {
+	if code != "" {
+		pterm.DefaultHeader.Printfln("Manifest dbg:%s", code)
+	} else {
+		pterm.DefaultHeader.Println("Manifest")
+	}
+	if m.MetaDetails.Time > 0 {
+		pterm.Info.Printfln("Created by %s on %s", m.Version, time.Unix(m.MetaDetails.Time, 0).Format(time.DateTime))
+	}
+	if s := m.System; s != nil {
+		pterm.Info.Printfln("OS: %s (%s)", s.OS, s.Arch)
+		if s.CPU != "" {
+			pterm.Info.Println("CPU:", s.CPU)
+		}
+		if s.MemoryTotal > 0 {
+			pterm.Info.Printfln("Memory: %s / %s", ByteCountIEC(s.MemoryUsed), ByteCountIEC(s.MemoryTotal))
+		}
+	}
+	if meta := m.AppDetails.Meta; meta.AppVersion != "" {
+		pterm.Info.Printfln("App version: %s (%s)", meta.AppVersion, meta.Branch)
+	}
+	pterm.Info.Printfln("Accounts: %d, active account: %t", m.MetaDetails.AddedAccounts, m.MetaDetails.HasActiveAccounts)
+	if m.Login != nil && m.Login.LoginProblem {
+		pterm.Warning.Println(m.Login.Verdict)
+	}
+
+	pterm.DefaultSection.Println("Network checks")
+	var passed int
+	for _, n := range m.NetworkChecks {
+		if n.Success {
+			passed++
+			continue
+		}
+		printNetworkCheck(n)
+	}
+	pterm.Info.Printfln("%d of %d checks passed", passed, len(m.NetworkChecks))
+	for _, d := range m.DNSChecks {
+		for _, p := range d.Problems {
+			pterm.Warning.Printfln("%s: %s", d.Host, p)
+		}
+	}
+
+	if len(m.Recommendations) > 0 {
+		pterm.DefaultSection.Println("Recommendations")
+		for _, r := range m.Recommendations {
+			pterm.Warning.Println(r)
+		}
+	}
+
+	pterm.DefaultSection.Println("Instances")
+	if len(m.ProviderInstanceMapping) == 0 {
+		pterm.Info.Println("No instances")
+		return
+	}
+	crashes := make(map[string]int)
+	for _, l := range m.InstanceLogs {
+		crashes[l.UUID] = len(l.CrashLogs)
+	}
+	instances := make([]Instances, 0, len(m.ProviderInstanceMapping))
+	for _, i := range m.ProviderInstanceMapping {
+		instances = append(instances, i)
+	}
+	slices.SortFunc(instances, func(a, b Instances) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	table := pterm.TableData{{"Name", "Minecraft", "Loader", "Memory", "Installed", "Crashes"}}
+	for _, i := range instances {
+		name := i.Name
+		if i.Orphaned {
+			name += " (orphaned)"
+		}
+		table = append(table, []string{name, i.McVersion, i.ModLoader, strconv.Itoa(i.Memory) + " MB", strconv.FormatBool(i.InstallComplete), strconv.Itoa(crashes[i.UUID])})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(table).Render()
+}
+
+// browseLogs lets the user pick an instance and then one of its uploaded logs
+// to print until they choose to exit
+func browseLogs(m Manifest) {
+	byLabel := make(map[string]InstanceLogs)
+	var labels []string
+	for _, l := range m.InstanceLogs {
+		if len(l.Logs) == 0 && len(l.CrashLogs) == 0 {
+			continue
+		}
+		label := fmt.Sprintf("%s (%s %s)", l.Name, l.McVersion, l.ModLoader)
+		if _, ok := byLabel[label]; ok {
+			label += " " + l.UUID
+		}
+		byLabel[label] = l
+		labels = append(labels, label)
+	}
+	slices.Sort(labels)
+	if len(m.AppLogs) > 0 {
+		labels = append([]string{inspectApp}, labels...)
+	}
+	if len(labels) == 0 {
+		return
+	}
+	labels = append(labels, inspectExit)
+
+	for {
+		choice, err := pterm.DefaultInteractiveSelect.WithOptions(labels).Show("Select an instance to view its logs")
+		if err != nil || choice == inspectExit {
+			return
+		}
+		logs := m.AppLogs
+		if choice != inspectApp {
+			logs = make(map[string]string)
+			for name, id := range byLabel[choice].Logs {
+				logs[name] = id
+			}
+			for name, id := range byLabel[choice].CrashLogs {
+				logs["crash: "+name] = id
+			}
+			for crash, excerpts := range byLabel[choice].CrashExcerpts {
+				for _, e := range excerpts {
+					logs[fmt.Sprintf("excerpt: %s around %s", e.Log, crash)] = e.Ref
+				}
+			}
+		}
+		browseInstanceLogs(m, logs)
+	}
+}
+
+func browseInstanceLogs(m Manifest, logs map[string]string) {
+	names := make([]string, 0, len(logs)+1)
+	for name := range logs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	names = append(names, inspectBack)
+
+	for {
+		choice, err := pterm.DefaultInteractiveSelect.WithOptions(names).Show("Select a log to view")
+		if err != nil || choice == inspectBack {
+			return
+		}
+		content, err := m.logContent(logs[choice])
+		if err != nil {
+			pterm.Error.Printfln("Failed to fetch %s: %s", choice, stripControl(err.Error()))
+			continue
+		}
+		pterm.DefaultSection.Println(stripControl(choice))
+		pterm.Println(stripControl(string(content)))
+	}
+}
+
+// stripControl removes control characters other than newlines and tabs from
+// text fetched from a paste, so a log can't move the cursor, recolour or
+// retitle the terminal with escape sequences
+func stripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r != '\n' && r != '\t' && unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,161 @@
+package dbg
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pterm/pterm"
+)
+
+// FTB packs have packType 0, everything else comes from other platforms
+const packTypeFTB = 0
+
+// findPackManifest looks for the file list of an FTB pack version in the
+// directory given on the command line, the app's .localCache and finally the
+// version.json the app writes into the instance
+func findPackManifest(instanceDir, root string, packId, versionId int) (PackVersionManifest, string, bool) {
+	pack, ver := strconv.Itoa(packId), strconv.Itoa(versionId)
+	var candidates []string
+	for _, dir := range []string{options.PackManifestDir, filepath.Join(root, ".localCache"), filepath.Join(ftbApp.InstallLocation, ".localCache")} {
+		if dir == "" {
+			continue
+		}
+		candidates = append(candidates,
+			filepath.Join(dir, pack, ver+".json"),
+			filepath.Join(dir, pack+"-"+ver+".json"),
+			filepath.Join(dir, "manifests", pack+"-"+ver+".json"),
+		)
+	}
+	candidates = append(candidates, filepath.Join(instanceDir, "version.json"))
+
+	for _, candidate := range candidates {
+		data, err := platform.FS.ReadFile(candidate)
+		if err != nil {
+			continue
+		}
+		var manifest PackVersionManifest
+		if err := json.Unmarshal(data, &manifest); err != nil || len(manifest.Files) == 0 {
+			continue
+		}
+		if manifest.ID != 0 && manifest.ID != versionId {
+			continue
+		}
+		return manifest, candidate, true
+	}
+	return PackVersionManifest{}, "", false
+}
+
+// verifyInstance compares the files on disk against the pack version's file
+// list. nil is returned when the instance isn't an FTB pack or no file list
+// could be found.
+func verifyInstance(instanceDir, root string, i Instance) *IntegrityReport {
+	if i.PackType != packTypeFTB || i.ID <= 0 || i.VersionID <= 0 {
+		return nil
+	}
+	manifest, source, ok := findPackManifest(instanceDir, root, i.ID, i.VersionID)
+	if !ok {
+		pterm.Debug.Printfln("No file list found for pack %d version %d", i.ID, i.VersionID)
+		return nil
+	}
+
+	report := &IntegrityReport{Source: source}
+	expected := make(map[string]bool)
+	var trackedDirs []string
+	for _, f := range manifest.Files {
+		if f.ServerOnly {
+			continue
+		}
+		rel := path.Clean(path.Join(strings.TrimPrefix(f.Path, "./"), f.Name))
+		file := filepath.Join(instanceDir, filepath.FromSlash(rel))
+		if !withinDir(instanceDir, file) {
+			pterm.Warning.Printfln("Ignoring %s from %s, it points outside the instance", rel, source)
+			continue
+		}
+		expected[rel] = true
+		if dir := path.Dir(rel); strings.HasSuffix(f.Name, ".jar") && !slices.Contains(trackedDirs, dir) {
+			trackedDirs = append(trackedDirs, dir)
+		}
+		report.Checked++
+
+		if f.Sha1 == "" {
+			if _, err := platform.FS.Stat(file); err != nil {
+				report.Missing = append(report.Missing, rel)
+			}
+			continue
+		}
+		sum, err := fileSha1(file)
+		if err != nil {
+			report.Missing = append(report.Missing, rel)
+			continue
+		}
+		if !strings.EqualFold(sum, f.Sha1) {
+			report.Modified = append(report.Modified, rel)
+		}
+	}
+
+	// Only directories holding pack jars are checked for extras, configs and
+	// saves are expected to change
+	for _, dir := range trackedDirs {
+		entries, err := platform.FS.ReadDir(filepath.Join(instanceDir, filepath.FromSlash(dir)))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			rel := path.Join(dir, e.Name())
+			if !e.IsDir() && strings.HasSuffix(e.Name(), ".jar") && !expected[rel] {
+				report.Extra = append(report.Extra, rel)
+			}
+		}
+	}
+	report.Complete = len(report.Missing) == 0
+	return report
+}
+
+// withinDir reports whether file, once cleaned, is dir or inside it
+func withinDir(dir, file string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(file))
+	return err == nil && filepath.IsLocal(rel)
+}
+
+// fileSha1 hashes a file without reading it into memory, pack jars can be
+// hundreds of MB
+func fileSha1(name string) (string, error) {
+	f, err := platform.FS.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha1.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func printIntegrityReport(name string, installComplete bool, r *IntegrityReport) {
+	if r.Complete && len(r.Modified) == 0 && len(r.Extra) == 0 {
+		pterm.Success.Printfln("%s: all %d pack files verified", name, r.Checked)
+		return
+	}
+	pterm.Warning.Printfln("%s: %d missing, %d modified, %d extra of %d pack files", name, len(r.Missing), len(r.Modified), len(r.Extra), r.Checked)
+	for _, f := range r.Missing {
+		pterm.Debug.Println("missing:", f)
+	}
+	for _, f := range r.Modified {
+		pterm.Debug.Println("modified:", f)
+	}
+	for _, f := range r.Extra {
+		pterm.Debug.Println("extra:", f)
+	}
+	if installComplete && !r.Complete {
+		addRecommendation(fmt.Sprintf("%s is marked as installed but %d pack files are missing, reinstall or repair the instance", name, len(r.Missing)))
+	}
+}
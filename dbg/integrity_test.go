@@ -0,0 +1,70 @@
+package dbg
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestVerifyInstance(t *testing.T) {
+	sum := func(s string) string {
+		h := sha1.Sum([]byte(s))
+		return hex.EncodeToString(h[:])
+	}
+	manifest, err := json.Marshal(PackVersionManifest{ID: 2, Files: []PackFile{
+		{Path: "./mods/", Name: "ok.jar", Sha1: sum("ok")},
+		{Path: "./mods/", Name: "changed.jar", Sha1: sum("original")},
+		{Path: "./mods/", Name: "gone.jar", Sha1: sum("gone")},
+		{Path: "./config/", Name: "any.toml"},
+		{Path: "./mods/", Name: "server.jar", ServerOnly: true},
+		{Path: "./mods/../../", Name: "outside.jar", Sha1: sum("outside")},
+		{Path: "../", Name: "missing-outside.jar"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	useFixture(t, fstest.MapFS{
+		"root/inst/version.json":     {Data: manifest},
+		"root/inst/mods/ok.jar":      {Data: []byte("ok")},
+		"root/inst/mods/changed.jar": {Data: []byte("changed")},
+		"root/inst/mods/extra.jar":   {Data: []byte("extra")},
+		"root/inst/config/any.toml":  {Data: []byte("edited")},
+		"root/outside.jar":           {Data: []byte("outside")},
+	}, Options{})
+
+	r := verifyInstance(fixturePath("root", "inst"), fixturePath("root"), Instance{ID: 1, VersionID: 2})
+	if r == nil {
+		t.Fatal("no report")
+	}
+	if r.Checked != 4 || r.Complete {
+		t.Errorf("Checked = %d, Complete = %t", r.Checked, r.Complete)
+	}
+	if !slices.Equal(r.Missing, []string{"mods/gone.jar"}) {
+		t.Errorf("Missing = %v", r.Missing)
+	}
+	if !slices.Equal(r.Modified, []string{"mods/changed.jar"}) {
+		t.Errorf("Modified = %v", r.Modified)
+	}
+	if !slices.Equal(r.Extra, []string{"mods/extra.jar"}) {
+		t.Errorf("Extra = %v", r.Extra)
+	}
+}
+
+func TestWithinDir(t *testing.T) {
+	dir := fixturePath("root", "inst")
+	tests := map[string]bool{
+		fixturePath("root", "inst"):                  true,
+		fixturePath("root", "inst", "mods", "a.jar"): true,
+		fixturePath("root", "inst", "..", "inst2"):   false,
+		fixturePath("root", "outside.jar"):           false,
+		fixturePath("root", "inst2", "a.jar"):        false,
+	}
+	for file, want := range tests {
+		if got := withinDir(dir, file); got != want {
+			t.Errorf("withinDir(%q) = %t, want %t", file, got, want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package dbg
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+var (
+	instanceSizeDirs = []string{"mods", "config", "saves", "logs", "crash-reports", "shaderpacks", "resourcepacks"}
+	// Directories that usually only grow this large when something is wrong
+	largeDirThresholds = map[string]int64{
+		"logs":          1 << 30,
+		"crash-reports": 256 << 20,
+		"saves":         20 << 30,
+	}
+)
+
+// instanceSize walks the instance once and breaks down its size by the
+// directories that matter for support. The second result holds the size of
+// every world folder in saves.
+func instanceSize(instanceDir string) (*InstanceSize, map[string]int64) {
+	size := &InstanceSize{Breakdown: make(map[string]int64)}
+	worlds := make(map[string]int64)
+	var walk func(dir, top, world string)
+	walk = func(dir, top, world string) {
+		entries, err := platform.FS.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				childTop, childWorld := top, world
+				if top == "" {
+					childTop = e.Name()
+				} else if top == "saves" && world == "" {
+					childWorld = e.Name()
+				}
+				walk(filepath.Join(dir, e.Name()), childTop, childWorld)
+				continue
+			}
+			info, err := e.Info()
+			if err != nil || info.Size() == 0 {
+				continue
+			}
+			size.Total += info.Size()
+			if slices.Contains(instanceSizeDirs, top) {
+				size.Breakdown[top] += info.Size()
+			}
+			if world != "" {
+				worlds[world] += info.Size()
+			}
+		}
+	}
+	walk(instanceDir, "", "")
+	return size, worlds
+}
+
+// listWorlds reads the level.dat of every world in the saves directory.
+// worldSizes comes from instanceSize so the worlds aren't walked again.
+func listWorlds(instanceDir string, worldSizes map[string]int64) []WorldInfo {
+	savesDir := filepath.Join(instanceDir, "saves")
+	entries, err := platform.FS.ReadDir(savesDir)
+	if err != nil {
+		return nil
+	}
+	var worlds []WorldInfo
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		worldDir := filepath.Join(savesDir, e.Name())
+		world := WorldInfo{Folder: e.Name(), Size: worldSizes[e.Name()]}
+		if info, err := e.Info(); err == nil && !info.ModTime().IsZero() {
+			world.Modified = info.ModTime().Unix()
+		}
+		data, err := platform.FS.ReadFile(filepath.Join(worldDir, "level.dat"))
+		if err != nil {
+			world.Error = "level.dat missing"
+			worlds = append(worlds, world)
+			continue
+		}
+		level, err := readLevelDat(bytes.NewReader(data))
+		if err != nil {
+			world.Error = fmt.Sprintf("level.dat unreadable: %s", err)
+			worlds = append(worlds, world)
+			continue
+		}
+		if d, ok := level["Data"].(map[string]any); ok {
+			world.Name, _ = d["LevelName"].(string)
+			if v, ok := d["DataVersion"].(int32); ok {
+				world.DataVersion = int(v)
+			}
+			if v, ok := d["LastPlayed"].(int64); ok {
+				world.LastPlayed = v / 1000
+			}
+			if version, ok := d["Version"].(map[string]any); ok {
+				world.GameVersion, _ = version["Name"].(string)
+			}
+		}
+		worlds = append(worlds, world)
+	}
+	return worlds
+}
+
+func printInstanceInventory(name string, size *InstanceSize, worlds []WorldInfo) {
+	pterm.Info.Printfln("%s: %s total", name, ByteCountIEC(size.Total))
+	for _, dir := range instanceSizeDirs {
+		s, ok := size.Breakdown[dir]
+		if !ok {
+			continue
+		}
+		if threshold, ok := largeDirThresholds[dir]; ok && s > threshold {
+			pterm.Warning.Printfln("  %s: %s, this is unusually large", dir, ByteCountIEC(s))
+			continue
+		}
+		pterm.Debug.Printfln("  %s: %s", dir, ByteCountIEC(s))
+	}
+	for _, w := range worlds {
+		if w.Error != "" {
+			pterm.Warning.Printfln("  world %s: %s", w.Folder, w.Error)
+			continue
+		}
+		lastPlayed := "never"
+		if w.LastPlayed > 0 {
+			lastPlayed = time.Unix(w.LastPlayed, 0).Format(time.DateTime)
+		}
+		pterm.Info.Printfln("  world %s (%s): %s, last played %s", w.Name, w.GameVersion, ByteCountIEC(w.Size), lastPlayed)
+	}
+}
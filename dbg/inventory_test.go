@@ -0,0 +1,44 @@
+package dbg
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestInstanceInventory(t *testing.T) {
+	useFixture(t, fstest.MapFS{
+		"inst/instance.json":              {Data: make([]byte, 1)},
+		"inst/mods/a.jar":                 {Data: make([]byte, 10)},
+		"inst/mods/nested/b.jar":          {Data: make([]byte, 20)},
+		"inst/saves/World/level.dat":      {Data: gzipped(t, levelDat().String())},
+		"inst/saves/World/region/r.0.mca": {Data: make([]byte, 100)},
+		"inst/saves/Broken/level.dat":     {Data: []byte("corrupt")},
+		"inst/saves/Empty/.keep":          {},
+		"inst/other/file":                 {Data: make([]byte, 5)},
+	}, Options{})
+	dir := fixturePath("inst")
+
+	size, worldSizes := instanceSize(dir)
+	levelSize := int64(len(gzipped(t, levelDat().String())))
+	if want := 1 + 30 + levelSize + 100 + 7 + 5; size.Total != want {
+		t.Errorf("Total = %d, want %d", size.Total, want)
+	}
+	want := map[string]int64{"mods": 30, "saves": levelSize + 107}
+	if len(size.Breakdown) != len(want) || size.Breakdown["mods"] != want["mods"] || size.Breakdown["saves"] != want["saves"] {
+		t.Errorf("Breakdown = %v, want %v", size.Breakdown, want)
+	}
+
+	worlds := map[string]WorldInfo{}
+	for _, w := range listWorlds(dir, worldSizes) {
+		worlds[w.Folder] = w
+	}
+	if w := worlds["World"]; w.Name != "New World" || w.GameVersion != "1.20.1" || w.DataVersion != 3465 || w.LastPlayed != 1700000000 || w.Size != levelSize+100 || w.Error != "" {
+		t.Errorf("World = %+v", w)
+	}
+	if w := worlds["Broken"]; w.Error == "" || w.Size != 7 {
+		t.Errorf("Broken = %+v", w)
+	}
+	if w := worlds["Empty"]; w.Error != "level.dat missing" || w.Size != 0 {
+		t.Errorf("Empty = %+v", w)
+	}
+}
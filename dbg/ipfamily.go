@@ -0,0 +1,127 @@
+package dbg
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// Hosts that have to fail over one family before changing it is suggested
+const minBrokenHosts = 2
+
+// runIPFamilyChecks repeats the first check of every host forced over IPv4
+// and then forced over IPv6
+func runIPFamilyChecks(checks []CheckURLStruct) []IPFamilyCheck {
+	var results []IPFamilyCheck
+	var seen []string
+	for _, check := range checks {
+		u, err := url.Parse(check.URL)
+		if err != nil || u.Hostname() == "" || slices.Contains(seen, u.Hostname()) {
+			continue
+		}
+		seen = append(seen, u.Hostname())
+		results = append(results, IPFamilyCheck{
+			Host: u.Hostname(),
+			IPv4: runFamilyCheck(check, u.Hostname(), "ip4", "tcp4"),
+			IPv6: runFamilyCheck(check, u.Hostname(), "ip6", "tcp6"),
+		})
+	}
+	return results
+}
+
+func runFamilyCheck(check CheckURLStruct, host, ipNetwork, tcpNetwork string) FamilyResult {
+	ctx, cancel := context.WithTimeout(context.Background(), dnsTimeout)
+	ips, err := net.DefaultResolver.LookupIP(ctx, ipNetwork, host)
+	cancel()
+	if (err == nil && len(ips) == 0) || noAddressRecords(err) {
+		return FamilyResult{NoAddress: true, Status: "no address records"}
+	}
+	if err != nil {
+		// Timeouts and server failures are what a broken AAAA setup looks like
+		return FamilyResult{Status: "DNS lookup failed: " + err.Error()}
+	}
+
+	dialer := &net.Dialer{Timeout: check.timeout()}
+	// No proxy, a proxy would connect with its own address family
+	transport := &http.Transport{
+		Proxy: nil,
+		DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, tcpNetwork, addr)
+		},
+	}
+	start := time.Now()
+	nc := runChecks([]CheckURLStruct{check}, transport)
+	result := FamilyResult{Duration: time.Since(start).Milliseconds()}
+	if len(nc) > 0 {
+		result.Ok = nc[0].Success
+		result.Status = nc[0].Status
+	}
+	return result
+}
+
+// noAddressRecords reports whether a lookup failed because the host has no
+// records of the family asked for, rather than because the lookup broke
+func noAddressRecords(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsNotFound
+	}
+	// The resolver answered but with addresses of the other family only
+	var addrErr *net.AddrError
+	return errors.As(err, &addrErr)
+}
+
+// ipFamilyRecommendation suggests JVM arguments when one address family is
+// consistently broken or much slower than the other. A single failing host is
+// more likely a problem with that host than with the connection.
+func ipFamilyRecommendation(results []IPFamilyCheck) string {
+	var v6Broken, v4Broken, v6Slow, dualStack int
+	for _, r := range results {
+		if r.IPv4.NoAddress || r.IPv6.NoAddress {
+			continue
+		}
+		dualStack++
+		if r.IPv4.Ok && !r.IPv6.Ok {
+			v6Broken++
+		} else if r.IPv6.Ok && !r.IPv4.Ok {
+			v4Broken++
+		} else if r.IPv4.Ok && r.IPv6.Ok && r.IPv6.Duration > 1000 && r.IPv6.Duration > r.IPv4.Duration*3 {
+			v6Slow++
+		}
+	}
+	switch {
+	case dualStack == 0:
+		return ""
+	case v6Broken >= minBrokenHosts:
+		return "IPv6 broken — consider adding -Djava.net.preferIPv4Stack=true to the JVM arguments"
+	case v4Broken >= minBrokenHosts:
+		return "IPv4 broken — consider adding -Djava.net.preferIPv6Addresses=true to the JVM arguments"
+	case v6Slow > dualStack/2:
+		return "IPv6 is much slower than IPv4 — consider adding -Djava.net.preferIPv4Stack=true to the JVM arguments"
+	}
+	return ""
+}
+
+func printIPFamilyCheck(r IPFamilyCheck) {
+	describe := func(f FamilyResult) string {
+		if f.NoAddress {
+			return "n/a"
+		}
+		if f.Ok {
+			return pterm.Sprintf("ok (%dms)", f.Duration)
+		}
+		return pterm.Sprintf("failed (%dms)", f.Duration)
+	}
+	line := pterm.Sprintf("%s: IPv4 %s, IPv6 %s", r.Host, describe(r.IPv4), describe(r.IPv6))
+	if (!r.IPv4.NoAddress && !r.IPv4.Ok) || (!r.IPv6.NoAddress && !r.IPv6.Ok) {
+		pterm.Warning.Println(line)
+		return
+	}
+	pterm.Success.Println(line)
+}
@@ -0,0 +1,51 @@
+package dbg
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestNoAddressRecords(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nxdomain", &net.DNSError{Err: "no such host", IsNotFound: true}, true},
+		{"only other family", &net.AddrError{Err: "no suitable address found"}, true},
+		{"timeout", &net.DNSError{Err: "i/o timeout", IsTimeout: true}, false},
+		{"servfail", &net.DNSError{Err: "server misbehaving", IsTemporary: true}, false},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		if got := noAddressRecords(tt.err); got != tt.want {
+			t.Errorf("%s: got %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIPFamilyRecommendation(t *testing.T) {
+	ok := FamilyResult{Ok: true, Duration: 50}
+	failed := FamilyResult{Status: "timeout"}
+	lookupFailed := FamilyResult{Status: "DNS lookup failed: server misbehaving"}
+	none := FamilyResult{NoAddress: true}
+
+	tests := []struct {
+		name    string
+		results []IPFamilyCheck
+		want    string
+	}{
+		{"one failing host", []IPFamilyCheck{{IPv4: ok, IPv6: failed}, {IPv4: ok, IPv6: ok}}, ""},
+		{"ipv6 broken", []IPFamilyCheck{{IPv4: ok, IPv6: failed}, {IPv4: ok, IPv6: lookupFailed}}, "IPv6 broken"},
+		{"ipv4 broken", []IPFamilyCheck{{IPv4: failed, IPv6: ok}, {IPv4: failed, IPv6: ok}}, "IPv4 broken"},
+		{"no ipv6 records", []IPFamilyCheck{{IPv4: ok, IPv6: none}, {IPv4: ok, IPv6: none}}, ""},
+	}
+	for _, tt := range tests {
+		got := ipFamilyRecommendation(tt.results)
+		if (tt.want == "") != (got == "") || !strings.HasPrefix(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package dbg
+
+import (
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// Logs uploaded whatever their age
+var alwaysUploadLogs = []string{"latest.log", "debug.log"}
+
+// selectLogs picks the files in dir worth uploading: latest.log and
+// debug.log, plus the newest options.LogCount files modified within
+// options.LogDays days. keepNewest always includes the newest file, which
+// is used so the last crash report is kept however old it is.
+func selectLogs(dir string, exts []string, keepNewest bool) ([]fs.DirEntry, error) {
+	entries, err := platform.FS.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type candidate struct {
+		entry    fs.DirEntry
+		modified time.Time
+	}
+	var candidates []candidate
+	for _, e := range entries {
+		if e.IsDir() || !slices.Contains(exts, filepath.Ext(e.Name())) {
+			continue
+		}
+		c := candidate{entry: e}
+		if info, err := e.Info(); err == nil {
+			c.modified = info.ModTime()
+		}
+		candidates = append(candidates, c)
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		return b.modified.Compare(a.modified)
+	})
+
+	var cutoff time.Time
+	if options.LogDays > 0 {
+		cutoff = time.Now().AddDate(0, 0, -options.LogDays)
+	}
+	var selected []fs.DirEntry
+	var recent int
+	for i, c := range candidates {
+		switch {
+		case slices.Contains(alwaysUploadLogs, c.entry.Name()), keepNewest && i == 0:
+		case options.LogCount > 0 && recent >= options.LogCount:
+			continue
+		case !cutoff.IsZero() && c.modified.Before(cutoff):
+			continue
+		default:
+			recent++
+		}
+		selected = append(selected, c.entry)
+	}
+	if skipped := len(candidates) - len(selected); skipped > 0 {
+		pterm.Debug.Printfln("Skipping %d older files in %s, use -log-count and -log-days to include them", skipped, dir)
+	}
+	return selected, nil
+}
+
+// gzipLog closes both the decompressor and the file under it
+type gzipLog struct {
+	*gzip.Reader
+	file io.Closer
+}
+
+func (g gzipLog) Close() error {
+	_ = g.Reader.Close()
+	return g.file.Close()
+}
+
+// openLog opens a log for reading a line at a time, decompressing .gz logs
+func openLog(path string) (io.ReadCloser, error) {
+	f, err := platform.FS.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Ext(path) != ".gz" {
+		return f, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return gzipLog{Reader: gz, file: f}, nil
+}
@@ -0,0 +1,68 @@
+package dbg
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestSelectLogs(t *testing.T) {
+	now := time.Now()
+	days := func(n int) time.Time { return now.AddDate(0, 0, -n) }
+	files := fstest.MapFS{
+		"logs/latest.log":            {ModTime: days(30)},
+		"logs/debug.log":             {ModTime: days(40)},
+		"logs/2024-01-01-1.log.gz":   {ModTime: days(1)},
+		"logs/app.log":               {ModTime: days(2)},
+		"logs/app-old.log":           {ModTime: days(3)},
+		"logs/app-older.log":         {ModTime: days(20)},
+		"logs/notes.json":            {ModTime: days(0)},
+		"logs/archive/nested.log":    {ModTime: days(0)},
+		"crash/crash-2020-01-01.txt": {ModTime: days(400)},
+		"crash/crash-2019-12-31.txt": {ModTime: days(401)},
+	}
+
+	tests := []struct {
+		name       string
+		dir        string
+		exts       []string
+		logCount   int
+		logDays    int
+		keepNewest bool
+		want       []string
+	}{
+		{"recent files only", "logs", []string{".log", ".gz"}, 5, 7, false,
+			[]string{"2024-01-01-1.log.gz", "app.log", "app-old.log", "latest.log", "debug.log"}},
+		{"count limit", "logs", []string{".log", ".gz"}, 1, 7, false,
+			[]string{"2024-01-01-1.log.gz", "latest.log", "debug.log"}},
+		{"no limits", "logs", []string{".log"}, 0, 0, false,
+			[]string{"app.log", "app-old.log", "app-older.log", "latest.log", "debug.log"}},
+		{"extension filter", "logs", []string{".json"}, 5, 7, false,
+			[]string{"notes.json"}},
+		{"old crash reports skipped", "crash", []string{".txt"}, 5, 7, false, nil},
+		{"newest crash report kept", "crash", []string{".txt"}, 5, 7, true,
+			[]string{"crash-2020-01-01.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFixture(t, files, Options{LogCount: tt.logCount, LogDays: tt.logDays})
+			entries, err := selectLogs(tt.dir, tt.exts, tt.keepNewest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Name())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	useFixture(t, files, Options{})
+	if _, err := selectLogs("missing", []string{".log"}, false); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
@@ -20,9 +20,18 @@ var (
 	re                   = regexp.MustCompile(`(?m)[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[1-5][0-9a-fA-F]{3}-[89abAB][0-9a-fA-F]{3}-[0-9a-fA-F]{12}`)
 	foundOverwolfVersion = false
 	failedToLoadSettings = false
+	options              Options
+	recommendations      []string
+	settingsProblems     []string
+	settingsDiff         []SettingDiff
+	// Set once the paste server rejects a gzip body
+	compressionUnsupported = false
 )
 
-func RunDebug() {
+func RunDebug(opts Options) {
+	options = opts
+	uploads = uploadQueue{}
+
 	var err error
 	logFile, err = os.CreateTemp("", "ftb-dbg-log")
 	if err != nil {
@@ -36,35 +45,77 @@ func RunDebug() {
 	pterm.SetDefaultOutput(logMw)
 
 	pterm.DefaultHeader.Println("System Info")
-	getOSInfo()
+	sysInfo := getOSInfo()
 	usr, err := user.Current()
 	if err != nil {
 		pterm.Error.Println("Failed to get users home directory")
+	} else if platform.HomeDir == "" {
+		platform.HomeDir = usr.HomeDir
 	}
 	ftbApp.User = usr
 
 	pterm.DefaultHeader.Println("Running Network Checks")
-	nc := runNetworkChecks()
+	checks, err := loadNetworkChecks(options.ChecksSource)
+	if err != nil {
+		pterm.Error.Println("Failed to load network checks, using built in checks:", err)
+		checks, _ = loadNetworkChecks("")
+	}
+	nc := runNetworkChecks(checks)
 	for _, n := range nc {
-		if n.Error {
-			pterm.Error.Println(n.Status)
-		} else if !n.Success && !n.Error {
-			pterm.Warning.Println(n.Status)
-		} else {
-			pterm.Success.Printfln("%s: %s", n.URL, n.Status)
-		}
+		printNetworkCheck(n)
+	}
+
+	pterm.DefaultSection.Println("Clock checks")
+	clockSkew, clockSkewSource, hasClockSkew := measureClockSkew(nc, options.NTPServer)
+	if !hasClockSkew {
+		pterm.Warning.Println("Unable to determine clock skew")
+	} else if clockSkew > options.ClockSkewThreshold || -clockSkew > options.ClockSkewThreshold {
+		pterm.Error.Println(clockSkewMessage(clockSkew))
+		addRecommendation(clockSkewMessage(clockSkew))
+	} else {
+		pterm.Success.Printfln("System clock is within %s (%s)", options.ClockSkewThreshold, clockSkewSource)
+	}
+
+	pterm.DefaultSection.Println("TLS checks")
+	printTLSChecks(nc)
+
+	pterm.DefaultSection.Println("IPv4/IPv6 checks")
+	ipFamilyChecks := runIPFamilyChecks(checks)
+	for _, r := range ipFamilyChecks {
+		printIPFamilyCheck(r)
+	}
+	addRecommendation(ipFamilyRecommendation(ipFamilyChecks))
+
+	pterm.DefaultSection.Println("DNS checks")
+	dnsChecks := runDNSChecks(checkHosts(checks), options.DNSResolvers)
+	for _, d := range dnsChecks {
+		printDNSCheck(d)
+	}
+	hostsEntries, err := getHostsFileEntries()
+	if err != nil {
+		pterm.Warning.Println("Unable to read hosts file:", err)
+	}
+	for _, entry := range hostsEntries {
+		pterm.Warning.Println("Hosts file entry:", entry)
 	}
 
 	pterm.DefaultHeader.Println("Running App Checks")
 	runAppChecks()
-	profiles, err := getProfiles()
+	profiles, profilesErr := getProfiles()
 	hasActiveAccount := false
-	if err != nil {
-		pterm.Error.Println("Failed to get profiles:", err)
+	if profilesErr != nil {
+		pterm.Error.Println("Failed to get profiles:", profilesErr)
 	} else {
 		hasActiveAccount = isActiveProfileInProfiles(profiles)
 	}
 
+	pterm.DefaultSection.WithLevel(2).Println("Account checks")
+	loginDiag := diagnoseLogin(profiles, profilesErr, nc)
+	printLoginDiagnostics(loginDiag)
+	if loginDiag.LoginProblem {
+		addRecommendation(loginDiag.Verdict)
+	}
+
 	pterm.DefaultSection.WithLevel(2).Println("App info")
 	pterm.Info.Println(fmt.Sprintf("Located app at %s", ftbApp.InstallLocation))
 	appVerData, err := getAppVersion()
@@ -76,20 +127,51 @@ func RunDebug() {
 		pterm.Info.Println("Branch:", appVerData.Branch)
 	}
 
+	pterm.DefaultSection.WithLevel(2).Println("Running processes")
+	processes := findProcesses(appVerData.Runtime.Jar)
+	printProcesses(processes)
+	for _, r := range processRecommendations(processes) {
+		addRecommendation(r)
+	}
+
+	pterm.DefaultSection.WithLevel(2).Println("Port checks")
+	portReport := checkPorts(appPorts(options.AppPorts), processes)
+	printPortReport(portReport)
+
 	appLogs := make(map[string]string)
 	instances := make(map[string]Instances)
 	instanceLogs := make([]InstanceLogs, 0)
 
-	appLogs, err = getAppLogs()
+	appLogs, uploadedAppLogs, err := getAppLogs()
 	if err != nil {
 		pterm.Error.Println("Failed to get app logs:", err)
 		return
 	}
-	if !failedToLoadSettings {
-		pterm.DefaultSection.Println("Check for instances")
-		instances, instanceLogs, err = getInstances()
-		if err != nil {
-			pterm.Error.Println("Failed to get instances:", err)
+	if failedToLoadSettings {
+		addRecommendation("settings.json could not be read, run this tool with the repair-settings command to fix it")
+	}
+	pterm.DefaultSection.Println("Check for instances")
+	instances, instanceLogs, err = getInstances(uploadedAppLogs)
+	if err != nil {
+		pterm.Error.Println("Failed to get instances:", err)
+	}
+	appLogExcerpts(instanceLogs, uploadedAppLogs)
+
+	pterm.DefaultSection.Println("Proxy checks")
+	proxyReport := detectProxies(instances)
+	compareProxyChecks(&proxyReport, checks)
+	printProxyReport(proxyReport)
+
+	var speedTests []SpeedTestResult
+	if options.SpeedTest {
+		pterm.DefaultSection.Println("Download speed test")
+		for _, target := range speedTestTargets(options.SpeedTestURLs) {
+			r := runSpeedTest(target, options.SpeedTestDuration)
+			printSpeedTestResult(r)
+			speedTests = append(speedTests, r)
+		}
+		for _, r := range speedTestRecommendations(speedTests, ftbApp.Settings) {
+			addRecommendation(r)
 		}
 	}
 
@@ -99,9 +181,9 @@ func RunDebug() {
 		filepath.Join(ftbApp.InstallLocation, "bin", "runtime", "installations.json"),
 	}
 	if foundOverwolfVersion {
-		miscFiles = append(miscFiles, filepath.Join(overwolfAppLogs, "index.html.log"))
-		miscFiles = append(miscFiles, filepath.Join(overwolfAppLogs, "background.html.log"))
-		miscFiles = append(miscFiles, filepath.Join(overwolfAppLogs, "chat.html.log"))
+		miscFiles = append(miscFiles, filepath.Join(platform.OverwolfAppLogs, "index.html.log"))
+		miscFiles = append(miscFiles, filepath.Join(platform.OverwolfAppLogs, "background.html.log"))
+		miscFiles = append(miscFiles, filepath.Join(platform.OverwolfAppLogs, "chat.html.log"))
 	}
 
 	for _, mf := range miscFiles {
@@ -113,6 +195,13 @@ func RunDebug() {
 		appLogs[filepath.Base(mf)] = id
 	}
 
+	if len(recommendations) > 0 {
+		pterm.DefaultHeader.Println("Recommendations")
+		for _, r := range recommendations {
+			pterm.Warning.Println(r)
+		}
+	}
+
 	tUpload, err := os.ReadFile(logFile.Name())
 	if err != nil {
 		pterm.Error.Println("Failed to read dbg output", logFile.Name())
@@ -130,7 +219,7 @@ func RunDebug() {
 	}
 
 	// Compile manifest
-	manifest.Version = "v2.0.5-go"
+	manifest.Version = toolVersion()
 	manifest.MetaDetails = MetaDetails{
 		InstanceCount:     len(instances),
 		Today:             time.Now().UTC().Format(time.DateOnly),
@@ -138,6 +227,11 @@ func RunDebug() {
 		AddedAccounts:     len(profiles.Profiles),
 		HasActiveAccounts: hasActiveAccount,
 	}
+	if hasClockSkew {
+		manifest.MetaDetails.ClockSkew = int64(clockSkew.Round(time.Second).Seconds())
+		manifest.MetaDetails.ClockSkewSource = clockSkewSource
+	}
+	manifest.System = &sysInfo
 	manifest.AppDetails = AppDetails{
 		App:           appVerData.Commit,
 		SharedVersion: appVerData.AppVersion,
@@ -147,6 +241,20 @@ func RunDebug() {
 	manifest.ProviderInstanceMapping = instances
 	manifest.InstanceLogs = instanceLogs
 	manifest.NetworkChecks = nc
+	manifest.DNSChecks = dnsChecks
+	manifest.HostsFileEntries = hostsEntries
+	manifest.Proxy = &proxyReport
+	manifest.IPFamilyChecks = ipFamilyChecks
+	manifest.SpeedTests = speedTests
+	manifest.Login = &loginDiag
+	manifest.Processes = processes
+	manifest.Ports = &portReport
+	manifest.SettingsProblems = settingsProblems
+	manifest.SettingsDiff = settingsDiff
+	manifest.Recommendations = recommendations
+
+	uploads.flush(&manifest)
+	manifest.SchemaVersion = manifest.schemaVersion()
 
 	pterm.DefaultHeader.Println("Manifest")
 	jsonManifest, err := json.MarshalIndent(manifest, "", "  ")
@@ -154,13 +262,41 @@ func RunDebug() {
 		pterm.Error.Println("Error marshalling manifest:", err)
 		return
 	}
+	for _, problem := range validateManifest(jsonManifest) {
+		pterm.Warning.Println("Manifest does not match its schema:", problem)
+	}
+	var code string
 	if len(jsonManifest) > 0 {
 		request, err := uploadRequest(jsonManifest, "json")
 		if err != nil {
 			pterm.Error.Println("Failed to upload manifest:", err)
-			return
+		} else {
+			code = request.Data.ID
+			codeStyle := pterm.NewStyle(pterm.FgLightMagenta, pterm.Bold)
+			pterm.DefaultBasicText.Printfln("Please provide this code to support: %s", codeStyle.Sprintf("dbg:%s", code))
+		}
+	}
+
+	if options.Summary || options.CopySummary {
+		summary := markdownSummary(manifest, code)
+		if options.Summary {
+			pterm.DefaultSection.Println("Summary")
+			pterm.Println(summary)
+		}
+		if options.CopySummary {
+			if err := copyToClipboard(summary); err != nil {
+				pterm.Error.Println("Failed to copy summary to the clipboard:", err)
+			} else {
+				pterm.Success.Println("Summary copied to the clipboard")
+			}
+		}
+	}
+
+	if options.HTMLReport != "" {
+		if err := writeHTMLReport(options.HTMLReport, manifest, code); err != nil {
+			pterm.Error.Println("Failed to write HTML report:", err)
+		} else {
+			pterm.Success.Println("HTML report written to", options.HTMLReport)
 		}
-		codeStyle := pterm.NewStyle(pterm.FgLightMagenta, pterm.Bold)
-		pterm.DefaultBasicText.Printfln("Please provide this code to support: %s", codeStyle.Sprintf("dbg:%s", request.Data.ID))
 	}
 }
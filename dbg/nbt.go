@@ -0,0 +1,155 @@
+package dbg
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// NBT tag types, see https://minecraft.wiki/w/NBT_format
+const (
+	nbtEnd byte = iota
+	nbtByte
+	nbtShort
+	nbtInt
+	nbtLong
+	nbtFloat
+	nbtDouble
+	nbtByteArray
+	nbtString
+	nbtList
+	nbtCompound
+	nbtIntArray
+	nbtLongArray
+)
+
+const (
+	// Real level.dat files are a few KB, anything near this is a gzip bomb
+	maxLevelDatSize = 16 << 20
+	maxNBTDepth     = 512
+)
+
+// readLevelDat decodes a gzipped NBT file such as level.dat into nested
+// map[string]any values. Arrays are skipped as nothing we report uses them.
+func readLevelDat(r io.Reader) (map[string]any, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	br := bufio.NewReader(io.LimitReader(gz, maxLevelDatSize))
+
+	tagType, err := br.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if tagType != nbtCompound {
+		return nil, errors.New("level.dat does not start with a compound tag")
+	}
+	if _, err := readNBTString(br); err != nil {
+		return nil, err
+	}
+	v, err := readNBTPayload(br, nbtCompound, 0)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("level.dat is truncated or larger than %s: %w", ByteCountIEC(maxLevelDatSize), io.ErrUnexpectedEOF)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]any), nil
+}
+
+func readNBTPayload(r *bufio.Reader, tagType byte, depth int) (any, error) {
+	if depth > maxNBTDepth {
+		return nil, errors.New("nbt nested too deeply")
+	}
+	switch tagType {
+	case nbtByte:
+		b, err := r.ReadByte()
+		return int8(b), err
+	case nbtShort:
+		var v int16
+		err := binary.Read(r, binary.BigEndian, &v)
+		return v, err
+	case nbtInt:
+		var v int32
+		err := binary.Read(r, binary.BigEndian, &v)
+		return v, err
+	case nbtLong:
+		var v int64
+		err := binary.Read(r, binary.BigEndian, &v)
+		return v, err
+	case nbtFloat:
+		var v uint32
+		err := binary.Read(r, binary.BigEndian, &v)
+		return math.Float32frombits(v), err
+	case nbtDouble:
+		var v uint64
+		err := binary.Read(r, binary.BigEndian, &v)
+		return math.Float64frombits(v), err
+	case nbtByteArray, nbtIntArray, nbtLongArray:
+		var n int32
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		size := map[byte]int64{nbtByteArray: 1, nbtIntArray: 4, nbtLongArray: 8}[tagType]
+		_, err := r.Discard(int(int64(max(n, 0)) * size))
+		return nil, err
+	case nbtString:
+		return readNBTString(r)
+	case nbtList:
+		elemType, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		var n int32
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		list := make([]any, 0, min(max(n, 0), 1024))
+		for i := int32(0); i < n; i++ {
+			v, err := readNBTPayload(r, elemType, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case nbtCompound:
+		compound := make(map[string]any)
+		for {
+			childType, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			if childType == nbtEnd {
+				return compound, nil
+			}
+			name, err := readNBTString(r)
+			if err != nil {
+				return nil, err
+			}
+			v, err := readNBTPayload(r, childType, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			compound[name] = v
+		}
+	default:
+		return nil, fmt.Errorf("unknown nbt tag type %d", tagType)
+	}
+}
+
+func readNBTString(r *bufio.Reader) (string, error) {
+	var n uint16
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	_, err := io.ReadFull(r, buf)
+	return string(buf), err
+}
@@ -0,0 +1,110 @@
+package dbg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// nbt builds raw NBT payloads for tests
+type nbt struct{ bytes.Buffer }
+
+func (b *nbt) tag(tagType byte, name string) *nbt {
+	b.WriteByte(tagType)
+	return b.str(name)
+}
+
+func (b *nbt) str(s string) *nbt {
+	_ = binary.Write(b, binary.BigEndian, uint16(len(s)))
+	b.WriteString(s)
+	return b
+}
+
+func (b *nbt) num(v any) *nbt {
+	_ = binary.Write(b, binary.BigEndian, v)
+	return b
+}
+
+func (b *nbt) end() *nbt {
+	b.WriteByte(nbtEnd)
+	return b
+}
+
+func levelDat() *nbt {
+	b := &nbt{}
+	b.tag(nbtCompound, "")
+	b.tag(nbtCompound, "Data")
+	b.tag(nbtString, "LevelName").str("New World")
+	b.tag(nbtInt, "DataVersion").num(int32(3465))
+	b.tag(nbtLong, "LastPlayed").num(int64(1700000000000))
+	b.tag(nbtIntArray, "WanderingTraderId").num(int32(4)).num([]int32{1, 2, 3, 4})
+	b.tag(nbtList, "ServerBrands").num(nbtString).num(int32(1)).str("forge")
+	b.tag(nbtCompound, "Version").tag(nbtString, "Name").str("1.20.1").end()
+	b.end()
+	return b.end()
+}
+
+func TestReadLevelDat(t *testing.T) {
+	level, err := readLevelDat(bytes.NewReader(gzipped(t, levelDat().String())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := level["Data"].(map[string]any)
+	if data["LevelName"] != "New World" || data["DataVersion"] != int32(3465) || data["LastPlayed"] != int64(1700000000000) {
+		t.Errorf("unexpected Data %v", data)
+	}
+	if brands := data["ServerBrands"].([]any); len(brands) != 1 || brands[0] != "forge" {
+		t.Errorf("ServerBrands = %v", brands)
+	}
+	if version := data["Version"].(map[string]any); version["Name"] != "1.20.1" {
+		t.Errorf("Version = %v", version)
+	}
+}
+
+func TestReadLevelDatInvalid(t *testing.T) {
+	valid := levelDat().String()
+	deep := &nbt{}
+	deep.tag(nbtCompound, "")
+	for range maxNBTDepth + 1 {
+		deep.tag(nbtCompound, "x")
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"not gzip", []byte("level.dat"), nil},
+		{"empty", gzipped(t, ""), io.EOF},
+		{"not a compound", gzipped(t, "\x08\x00\x00"), nil},
+		{"unknown tag", gzipped(t, (&nbt{}).tag(nbtCompound, "").tag(42, "x").String()), nil},
+		{"truncated in name", gzipped(t, valid[:10]), io.ErrUnexpectedEOF},
+		{"truncated in value", gzipped(t, valid[:len(valid)-12]), io.ErrUnexpectedEOF},
+		{"missing end tags", gzipped(t, valid[:len(valid)-2]), io.ErrUnexpectedEOF},
+		{"huge array", gzipped(t, (&nbt{}).tag(nbtCompound, "").tag(nbtLongArray, "x").num(int32(0x7fffffff)).String()), io.ErrUnexpectedEOF},
+		{"huge list", gzipped(t, (&nbt{}).tag(nbtCompound, "").tag(nbtList, "x").num(nbtLong).num(int32(0x7fffffff)).String()), io.ErrUnexpectedEOF},
+		{"list of end tags", gzipped(t, (&nbt{}).tag(nbtCompound, "").tag(nbtList, "x").num(nbtEnd).num(int32(3)).String()), nil},
+		{"nested too deeply", gzipped(t, deep.String()), nil},
+		{"gzip bomb", gzipped(t, (&nbt{}).tag(nbtCompound, "").tag(nbtString, "x").str("a").String()+strings.Repeat("\x08\x00\x00\x00\x00", maxLevelDatSize/5+1)), io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, err := readLevelDat(bytes.NewReader(tt.data))
+			if err == nil {
+				t.Fatalf("got %v, want an error", level)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// A negative array length is read as an empty array
+	negative := (&nbt{}).tag(nbtCompound, "").tag(nbtByteArray, "x").num(int32(-1)).end()
+	if _, err := readLevelDat(bytes.NewReader(gzipped(t, negative.String()))); err != nil {
+		t.Errorf("negative array length: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package dbg
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultPasteURL = "https://pste.me"
+
+// pasteBase returns the paste server to upload to and read from, without a
+// trailing slash
+func pasteBase() string {
+	if options.PasteURL != "" {
+		return strings.TrimRight(options.PasteURL, "/")
+	}
+	return defaultPasteURL
+}
+
+// pasteLink is where a paste can be viewed in a browser
+func pasteLink(id string) string {
+	return pasteBase() + "/#/" + id
+}
+
+// fetchPaste downloads the raw content of a paste. id may have been typed by
+// the user so it is escaped rather than trusted to be a single path segment.
+func fetchPaste(id string) ([]byte, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("%s/v1/paste/%s/raw", pasteBase(), url.PathEscape(id)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("invalid status code: %d\n%s", resp.StatusCode, string(content))
+	}
+	return content, nil
+}
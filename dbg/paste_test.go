@@ -0,0 +1,168 @@
+package dbg
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+// pasteServer is a local stand-in for the paste server
+type pasteServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	pastes map[string][]byte
+}
+
+func newPasteServer(t *testing.T) *pasteServer {
+	t.Helper()
+	s := &pasteServer{pastes: make(map[string][]byte)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *pasteServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPut && r.URL.Path == "/v1/paste":
+		body := io.Reader(r.Body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			body = gz
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		id := fmt.Sprintf("p%d", len(s.pastes)+1)
+		s.pastes[id] = data
+		_ = json.NewEncoder(w).Encode(PsteMeResp{Ok: true, Data: PsteMeData{ID: id}})
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/paste/") && strings.HasSuffix(r.URL.Path, "/raw"):
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/paste/"), "/raw")
+		data, ok := s.pastes[id]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	default:
+		http.Error(w, "unexpected request", http.StatusMethodNotAllowed)
+	}
+}
+
+func TestPasteURL(t *testing.T) {
+	srv := newPasteServer(t)
+	useFixture(t, fstest.MapFS{}, Options{PasteURL: srv.URL + "/"})
+
+	if got, want := pasteLink("abc"), srv.URL+"/#/abc"; got != want {
+		t.Errorf("pasteLink = %q, want %q", got, want)
+	}
+	resp, err := uploadRequest([]byte("hello\n"), "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := fetchPaste(resp.Data.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello\n" {
+		t.Errorf("fetched %q", data)
+	}
+	if _, err := fetchPaste("missing"); err == nil {
+		t.Error("expected an error for a missing paste")
+	}
+	// Without escaping this would request /v1/paste/p1/raw
+	if data, err := fetchPaste(resp.Data.ID + "/raw?x="); err == nil {
+		t.Errorf("code with a path and query fetched %q", data)
+	}
+}
+
+func TestLoadManifestAndLogContent(t *testing.T) {
+	srv := newPasteServer(t)
+	files := map[string]string{
+		"small.log":  "tiny\n",
+		"first.log":  strings.Repeat("first line\n", 40),
+		"second.log": strings.Repeat("second line\n", 30) + "no trailing newline",
+		"third.log":  strings.Repeat("third line\n", 50),
+		"large.log":  strings.Repeat("large line\n", 400),
+	}
+	useFixture(t, fstest.MapFS{}, Options{PasteURL: srv.URL, InlineLimit: 16, BatchLimit: 1024})
+
+	refs := make(map[string]string)
+	for _, name := range []string{"small.log", "first.log", "second.log", "third.log", "large.log"} {
+		ref, err := uploads.add(name, []byte(files[name]), "log")
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs[name] = ref
+	}
+	for name, want := range map[string]string{"small.log": "inline", "first.log": "batch", "second.log": "batch", "third.log": "batch", "large.log": "paste"} {
+		if kind, _, _, _ := parseLogRef(refs[name]); kind != want {
+			t.Errorf("%s placed as %s, want %s", name, kind, want)
+		}
+	}
+
+	var manifest Manifest
+	manifest.AppLogs = refs
+	uploads.flush(&manifest)
+	manifest.SchemaVersion = manifestSchemaVersion
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := uploadRequest(data, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	useFixture(t, fstest.MapFS{"support/manifest.json": {Data: data}}, Options{PasteURL: srv.URL})
+	for _, target := range []string{"dbg:" + resp.Data.ID, resp.Data.ID, fixturePath("support", "manifest.json")} {
+		t.Run(target, func(t *testing.T) {
+			loaded, _, err := loadManifest(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, ref := range loaded.AppLogs {
+				content, err := loaded.logContent(ref)
+				if err != nil {
+					t.Fatalf("%s: %s", name, err)
+				}
+				want := files[name]
+				if !strings.HasSuffix(want, "\n") {
+					want += "\n"
+				}
+				if string(content) != want {
+					t.Errorf("%s (%s) = %q, want %q", name, ref, content, want)
+				}
+			}
+		})
+	}
+
+	if _, _, err := loadManifest("dbg:missing"); err == nil {
+		t.Error("expected an error for a missing manifest paste")
+	}
+	if _, _, err := loadManifest("dbg:"); err == nil {
+		t.Error("expected an error for an empty code")
+	}
+}
+
+func TestStripControl(t *testing.T) {
+	// A raw 0x9b byte isn't valid UTF-8 and is replaced rather than passed on
+	log := "[12:00:00] \x1b[31mred\x1b[0m\r\n\ttrace\x1b]0;owned\x07 \u009b2J\x9b2Jdone\x00\n"
+	if got, want := stripControl(log), "[12:00:00] [31mred[0m\n\ttrace]0;owned 2J\ufffd2Jdone\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
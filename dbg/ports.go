@@ -0,0 +1,226 @@
+package dbg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+	psnet "github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Lines logged when the app's backend or websocket server starts, e.g.
+// "Websocket server started on port 13377" or "backend listening on
+// 127.0.0.1:13377"
+var serverPortRe = regexp.MustCompile(`(?i)(websocket|socket server|backend)[^\r\n]{0,80}?(?:port\D{0,5}|(?:127\.0\.0\.1|localhost|\[::1\]):)(\d{2,5})\b`)
+
+// Addresses a listener can hold that stop the app binding its loopback ports
+var loopbackListenAddrs = []string{"127.0.0.1", "::1", "0.0.0.0", "::", "*", ""}
+
+// appPorts collects the localhost ports the app's frontend and backend talk
+// over from its newest log, its settings and the command line. Older logs are
+// ignored as the app picks new ports between runs.
+func appPorts(extra []int) map[int]string {
+	ports := make(map[int]string)
+	for _, p := range extra {
+		ports[p] = "command line"
+	}
+	for key, value := range ftbApp.Settings.Extra {
+		if !strings.Contains(strings.ToLower(key), "port") {
+			continue
+		}
+		if p, err := strconv.Atoi(strings.Trim(string(value), `"`)); err == nil && validPort(p) {
+			ports[p] = "settings " + key
+		}
+	}
+
+	lPath := filepath.Join(ftbApp.InstallLocation, "logs")
+	name, ok := newestLog(lPath, ".log")
+	if !ok {
+		return ports
+	}
+	data, err := platform.FS.ReadFile(filepath.Join(lPath, name))
+	if err != nil {
+		return ports
+	}
+	// A log can span several starts, only the last port of each server counts
+	last := make(map[string]int)
+	for _, match := range serverPortRe.FindAllSubmatch(data, -1) {
+		if p, err := strconv.Atoi(string(match[2])); err == nil && validPort(p) {
+			last[strings.ToLower(string(match[1]))] = p
+		}
+	}
+	for _, p := range last {
+		if _, ok := ports[p]; !ok {
+			ports[p] = "log " + name
+		}
+	}
+	return ports
+}
+
+// newestLog returns the most recently modified file with ext in dir
+func newestLog(dir, ext string) (string, bool) {
+	entries, err := platform.FS.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	var newest string
+	var newestTime time.Time
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ext {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		if newest == "" || info.ModTime().After(newestTime) {
+			newest, newestTime = e.Name(), info.ModTime()
+		}
+	}
+	return newest, newest != ""
+}
+
+func validPort(p int) bool {
+	return p > 0 && p <= 65535
+}
+
+// checkPorts reports who is listening on each of the app's ports. A port held
+// by anything other than the app or its backend is a conflict.
+func checkPorts(ports map[int]string, procs []ProcessInfo) PortReport {
+	var report PortReport
+	report.LoopbackOk, report.LoopbackError = loopbackTest()
+
+	type listenAddr struct {
+		ip   string
+		port uint32
+	}
+	listeners := make(map[listenAddr]int32)
+	if conns, err := psnet.Connections("tcp"); err == nil {
+		for _, c := range conns {
+			if c.Status == "LISTEN" {
+				listeners[listenAddr{c.Laddr.IP, c.Laddr.Port}] = c.Pid
+			}
+		}
+	} else {
+		pterm.Debug.Println("Unable to list listening ports:", err)
+	}
+	// The app, its backend and the instances it launched are expected to
+	// hold localhost ports
+	knownPids := make(map[int32]bool)
+	for _, p := range procs {
+		if p.Kind == "app" || p.Kind == "backend" || p.Kind == "minecraft" {
+			knownPids[p.PID] = true
+		}
+	}
+
+	sorted := make([]int, 0, len(ports))
+	for p := range ports {
+		sorted = append(sorted, p)
+	}
+	slices.Sort(sorted)
+	for _, port := range sorted {
+		check := PortCheck{Port: port, Source: ports[port]}
+		for _, ip := range loopbackListenAddrs {
+			pid, ok := listeners[listenAddr{ip, uint32(port)}]
+			if !ok {
+				continue
+			}
+			// Prefer reporting the app when it shares the port with others
+			if check.Bound && !check.Conflict {
+				break
+			}
+			check.Bound = true
+			check.OwnerPID = pid
+			check.Conflict = pid != 0 && !knownPids[pid]
+		}
+		if check.Bound {
+			if proc, err := process.NewProcess(check.OwnerPID); err == nil {
+				check.Owner, _ = proc.Name()
+			}
+			check.Reachable = dialLoopback(port) == nil
+		}
+		report.Ports = append(report.Ports, check)
+	}
+	return report
+}
+
+// loopbackTest sends data over a localhost connection to catch firewalls and
+// security software that block loopback traffic
+func loopbackTest() (bool, string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return false, err.Error()
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		_, _ = io.Copy(conn, io.LimitReader(conn, 4))
+	}()
+
+	conn, err := net.DialTimeout("tcp", ln.Addr().String(), 5*time.Second)
+	if err != nil {
+		return false, err.Error()
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		return false, err.Error()
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return false, err.Error()
+	}
+	if !bytes.Equal(buf, []byte("ping")) {
+		return false, "loopback data was altered"
+	}
+	return true, ""
+}
+
+func dialLoopback(port int) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), 2*time.Second)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func printPortReport(report PortReport) {
+	if report.LoopbackOk {
+		pterm.Success.Println("Loopback connections work")
+	} else {
+		pterm.Error.Println("Loopback connection failed, a firewall may be blocking localhost:", report.LoopbackError)
+		addRecommendation("Connections to localhost are blocked, allow the FTB App through the firewall or antivirus")
+	}
+	if len(report.Ports) == 0 {
+		pterm.Info.Println("No app ports found in the logs or settings")
+		return
+	}
+	for _, p := range report.Ports {
+		switch {
+		case p.Conflict:
+			msg := fmt.Sprintf("Port %d (from %s) is in use by %s (pid %d), the app will hang on load", p.Port, p.Source, p.Owner, p.OwnerPID)
+			pterm.Error.Println(msg)
+			addRecommendation(msg)
+		case p.Bound && !p.Reachable:
+			pterm.Warning.Printfln("Port %d (from %s) is bound by %s but can't be connected to", p.Port, p.Source, p.Owner)
+		case p.Bound:
+			pterm.Success.Printfln("Port %d (from %s) is bound by %s", p.Port, p.Source, p.Owner)
+		default:
+			pterm.Info.Printfln("Port %d (from %s) is free", p.Port, p.Source)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package dbg
+
+import (
+	"maps"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestAppPorts(t *testing.T) {
+	now := time.Now()
+	useFixture(t, fstest.MapFS{
+		"ftba/logs/latest.log": {ModTime: now, Data: []byte(
+			"[12:00:00] Websocket server started on port 13377\n" +
+				"[12:00:01] Connecting to http://localhost:8080/api\n" +
+				"[12:05:00] Websocket server started on port 13378\n" +
+				"[12:05:01] backend listening on 127.0.0.1:24000\n")},
+		"ftba/logs/old.log": {ModTime: now.Add(-time.Hour), Data: []byte("Websocket server started on port 40000\n")},
+	}, Options{})
+	ftbApp.InstallLocation = fixturePath("ftba")
+
+	got := appPorts([]int{5000})
+	want := map[int]string{5000: "command line", 13378: "log latest.log", 24000: "log latest.log"}
+	if !maps.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,179 @@
+package dbg
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+var (
+	appProcessNames      = []string{"ftb-app", "ftb app", "ftbapp", "ftb electron app"}
+	minecraftMainClasses = []string{"net.minecraft.", "cpw.mods.", "net.fabricmc.", "net.neoforged.", "net.minecraftforge.", "org.quiltmc.", "--gameDir"}
+	// Launch arguments whose values should never leave the machine
+	secretArgs = []string{"--accessToken", "--uuid", "--username", "--xuid", "--clientId", "--session"}
+	// JVM properties such as -Dauth.accessToken=... or -Dminecraft.session=...
+	secretPropRe = regexp.MustCompile(`(?i)^(-D[^=]*(?:auth|token|session|password|secret)[^=]*=).+`)
+)
+
+// findProcesses returns the running FTB App, its Java backend and any
+// Minecraft instances. backendJar is the runtime jar from the app meta.json.
+func findProcesses(backendJar string) []ProcessInfo {
+	procs, err := process.Processes()
+	if err != nil {
+		pterm.Error.Println("Unable to list processes:", err)
+		return nil
+	}
+	backendJar = filepath.Base(backendJar)
+
+	var found []ProcessInfo
+	for _, p := range procs {
+		name, _ := p.Name()
+		args, _ := p.CmdlineSlice()
+		cmdline := strings.Join(args, " ")
+		kind := classifyProcess(name, cmdline, backendJar)
+		if kind == "" {
+			continue
+		}
+
+		info := ProcessInfo{PID: p.Pid, Kind: kind, Name: name, Cmdline: sanitizeCmdline(args)}
+		if m, err := p.MemoryInfo(); err == nil {
+			info.Memory = int64(m.RSS)
+		}
+		if cpuPercent, err := p.CPUPercent(); err == nil {
+			info.CPUPercent = cpuPercent
+		}
+		if created, err := p.CreateTime(); err == nil {
+			info.Started = created / 1000
+		}
+		if conns, err := p.Connections(); err == nil {
+			for _, c := range conns {
+				if c.Status == "LISTEN" && !slices.Contains(info.ListenPorts, c.Laddr.Port) {
+					info.ListenPorts = append(info.ListenPorts, c.Laddr.Port)
+				}
+			}
+		}
+		if kind == "minecraft" {
+			dir := gameDir(p, args)
+			info.Instance = instanceNameForDir(dir)
+			info.InstanceDir = string(sanitize([]byte(dir)))
+		}
+		found = append(found, info)
+	}
+	return found
+}
+
+func classifyProcess(name, cmdline, backendJar string) string {
+	lowerName := strings.ToLower(name)
+	lowerCmd := strings.ToLower(cmdline)
+	for _, appName := range appProcessNames {
+		if strings.Contains(lowerName, appName) {
+			return "app"
+		}
+	}
+	if strings.Contains(lowerName, "overwolf") && strings.Contains(cmdline, owUID) {
+		return "app"
+	}
+	if !strings.Contains(lowerName, "java") {
+		return ""
+	}
+	if (backendJar != "" && backendJar != "." && strings.Contains(cmdline, backendJar)) || strings.Contains(lowerCmd, ".ftba") && strings.Contains(lowerCmd, "-jar") {
+		return "backend"
+	}
+	for _, class := range minecraftMainClasses {
+		if strings.Contains(cmdline, class) {
+			return "minecraft"
+		}
+	}
+	return ""
+}
+
+func gameDir(p *process.Process, args []string) string {
+	for i, arg := range args {
+		if arg == "--gameDir" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	cwd, _ := p.Cwd()
+	return cwd
+}
+
+// instanceNameForDir reads instance.json from dir to find which instance a
+// Minecraft process belongs to
+func instanceNameForDir(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	data, err := platform.FS.ReadFile(filepath.Join(dir, "instance.json"))
+	if err != nil {
+		return ""
+	}
+	var i Instance
+	if err := json.Unmarshal(data, &i); err != nil {
+		return ""
+	}
+	return i.Name
+}
+
+// sanitizeCmdline hides launch secrets, given as "--accessToken tok",
+// "--accessToken=tok" or a JVM property, and user names in paths
+func sanitizeCmdline(args []string) string {
+	clean := make([]string, len(args))
+	for i, arg := range args {
+		if i > 0 && slices.Contains(secretArgs, args[i-1]) {
+			clean[i] = "***"
+			continue
+		}
+		if key, _, ok := strings.Cut(arg, "="); ok && slices.Contains(secretArgs, key) {
+			clean[i] = key + "=***"
+			continue
+		}
+		// Each argument is sanitized on its own so tokens match from its start
+		clean[i] = string(sanitize([]byte(secretPropRe.ReplaceAllString(arg, "${1}***"))))
+	}
+	return strings.Join(clean, " ")
+}
+
+// processRecommendations flags background processes that commonly stop the
+// app from starting
+func processRecommendations(procs []ProcessInfo) []string {
+	var apps, backends int
+	for _, p := range procs {
+		switch p.Kind {
+		case "app":
+			apps++
+		case "backend":
+			backends++
+		}
+	}
+	var recs []string
+	if backends > 0 && apps == 0 {
+		recs = append(recs, "The FTB App backend is running without the app, end the Java process in the task manager before starting the app")
+	}
+	if backends > 1 {
+		recs = append(recs, "Multiple FTB App backends are running, end them all in the task manager and restart the app")
+	}
+	return recs
+}
+
+func printProcesses(procs []ProcessInfo) {
+	if len(procs) == 0 {
+		pterm.Info.Println("No FTB App or Minecraft processes running")
+		return
+	}
+	for _, p := range procs {
+		line := pterm.Sprintf("%s (pid %d, %s): %s, %.1f%% CPU", p.Kind, p.PID, p.Name, ByteCountIEC(p.Memory), p.CPUPercent)
+		if len(p.ListenPorts) > 0 {
+			line += pterm.Sprintf(", listening on %v", p.ListenPorts)
+		}
+		if p.Instance != "" {
+			line += ", instance " + p.Instance
+		}
+		pterm.Info.Println(line)
+		pterm.Debug.Println(p.Cmdline)
+	}
+}
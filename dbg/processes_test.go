@@ -0,0 +1,55 @@
+package dbg
+
+import "testing"
+
+func TestSanitizeCmdline(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"separate value", []string{"java", "--username", "Steve", "--accessToken", "abc.def", "--version", "1.20.1"},
+			"java --username *** --accessToken *** --version 1.20.1"},
+		{"joined value", []string{"java", "--accessToken=abc.def", "--uuid=0123", "--versionType=release"},
+			"java --accessToken=*** --uuid=*** --versionType=release"},
+		{"secret as the last argument", []string{"java", "--accessToken"}, "java --accessToken"},
+		{"auth properties", []string{"java", "-Dauth.accessToken=abc", "-DauthServer=https://auth.example", "-Dminecraft.session=xyz", "-Dfile.encoding=UTF-8", "-Dlog4j2.formatMsgNoLookups=true"},
+			"java -Dauth.accessToken=*** -DauthServer=*** -Dminecraft.session=*** -Dfile.encoding=UTF-8 -Dlog4j2.formatMsgNoLookups=true"},
+		{"bare token", []string{"java", "eyJhbGciOiJIUzI1NiJ9.payload.sig"}, "java ******AUTHTOKEN******"},
+		{"user name in path", []string{"/home/steve/.ftba/runtime/bin/java", "-cp", `C:\Users\Steve\lib.jar`},
+			`/home/***/.ftba/runtime/bin/java -cp C:\Users\***\lib.jar`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeCmdline(tt.args); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyProcess(t *testing.T) {
+	tests := []struct {
+		name, procName, cmdline, backendJar string
+		want                                string
+	}{
+		{"electron app", "FTB App.exe", `C:\Program Files\FTB App\FTB App.exe`, "", "app"},
+		{"linux app", "ftb-app", "/opt/ftb-app/ftb-app --no-sandbox", "", "app"},
+		{"overwolf app", "Overwolf.exe", "Overwolf.exe -launchapp " + owUID, "", "app"},
+		{"other overwolf app", "Overwolf.exe", "Overwolf.exe -launchapp someotherapp", "", ""},
+		{"backend by jar", "java", "java -jar /home/me/.ftba/bin/launcher-1.2.3.jar", "bin/launcher-1.2.3.jar", "backend"},
+		{"backend by location", "javaw.exe", `javaw.exe -jar C:\Users\me\AppData\Local\.ftba\bin\app.jar`, "", "backend"},
+		{"forge client", "java", "java -cp libs cpw.mods.bootstraplauncher.BootstrapLauncher --gameDir /i/pack", "", "minecraft"},
+		{"fabric client", "javaw.exe", "javaw.exe net.fabricmc.loader.impl.launch.knot.KnotClient", "", "minecraft"},
+		{"vanilla client", "java", "java net.minecraft.client.main.Main --gameDir /i/pack", "", "minecraft"},
+		{"unrelated java", "java", "java -jar gradle-wrapper.jar", "", ""},
+		{"minecraft class in a non java process", "grep", "grep net.minecraft.", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyProcess(tt.procName, tt.cmdline, tt.backendJar); got != tt.want {
+				t.Errorf("classifyProcess(%q, %q) = %q, want %q", tt.procName, tt.cmdline, got, tt.want)
+			}
+		})
+	}
+}
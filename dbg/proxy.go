@@ -0,0 +1,269 @@
+package dbg
+
+import (
+	"cmp"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pterm/pterm"
+)
+
+var (
+	proxyEnvVars      = []string{"HTTP_PROXY", "HTTPS_PROXY", "ALL_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "all_proxy", "no_proxy"}
+	javaProxyPropRe   = regexp.MustCompile(`-D(https?\.proxyHost|https?\.proxyPort|http\.nonProxyHosts|socksProxyHost|socksProxyPort|java\.net\.useSystemProxies)=("[^"]*"|\S+)`)
+	javaProxySchemes  = map[string]string{"http": "http", "https": "http", "socks": "socks5"}
+	javaProxyHostKeys = []string{"http", "https", "socks"}
+)
+
+// detectProxies collects proxies configured in the environment, the OS and
+// the JVM arguments of the app and its instances
+func detectProxies(instances map[string]Instances) ProxyReport {
+	report := ProxyReport{Environment: make(map[string]string)}
+	noProxy := splitNoProxy(cmp.Or(os.Getenv("NO_PROXY"), os.Getenv("no_proxy")), ",")
+	seen := make(map[string]bool)
+	for _, name := range proxyEnvVars {
+		// Windows environment variables ignore case, HTTP_PROXY and
+		// http_proxy are the same variable there
+		if platform.GOOS == "windows" {
+			if seen[strings.ToUpper(name)] {
+				continue
+			}
+			seen[strings.ToUpper(name)] = true
+		}
+		if v := os.Getenv(name); v != "" {
+			if strings.HasSuffix(strings.ToUpper(name), "NO_PROXY") {
+				report.Environment[name] = v
+				continue
+			}
+			report.Environment[name] = redactProxy(v)
+			if u := parseProxyURL(v, "http"); u != nil {
+				report.addProxy("environment "+name, u, noProxy)
+			}
+		}
+	}
+
+	system, systemBypass, err := getSystemProxy()
+	if err != nil {
+		pterm.Debug.Println("Unable to read system proxy settings:", err)
+	}
+	for source, proxy := range system {
+		report.System = append(report.System, fmt.Sprintf("%s: %s", source, redactProxy(proxy)))
+		// PAC scripts can't be evaluated here so they are only reported
+		if source == "pac" {
+			continue
+		}
+		if u := parseProxyURL(proxy, "http"); u != nil {
+			report.addProxy("system "+source, u, systemBypass)
+		}
+	}
+	if len(systemBypass) > 0 {
+		report.System = append(report.System, "bypass: "+strings.Join(systemBypass, ";"))
+	}
+
+	report.addJavaProxies("app settings", ftbApp.Settings.Jvmargs)
+	for _, instance := range instances {
+		report.addJavaProxies("instance "+instance.Name, instance.JvmArgs)
+	}
+	return report
+}
+
+// addProxy records a proxy found in source. noProxy lists the hosts that
+// source sends directly instead of through the proxy.
+func (r *ProxyReport) addProxy(source string, proxy *url.URL, noProxy []string) {
+	for i, p := range r.Detected {
+		if p.proxy.String() == proxy.String() {
+			r.Detected[i].Sources = append(r.Detected[i].Sources, source)
+			r.Detected[i].noProxy = append(r.Detected[i].noProxy, noProxy...)
+			return
+		}
+	}
+	r.Detected = append(r.Detected, DetectedProxy{URL: redactProxyURL(proxy), Sources: []string{source}, proxy: proxy, noProxy: noProxy})
+}
+
+func (r *ProxyReport) addJavaProxies(source, jvmArgs string) {
+	props := make(map[string]string)
+	for _, match := range javaProxyPropRe.FindAllStringSubmatch(jvmArgs, -1) {
+		props[match[1]] = strings.Trim(match[2], `"`)
+		r.JavaProperties = append(r.JavaProperties, fmt.Sprintf("%s: %s", source, match[0]))
+	}
+	for _, key := range javaProxyHostKeys {
+		hostKey, portKey := key+".proxyHost", key+".proxyPort"
+		if key == "socks" {
+			hostKey, portKey = "socksProxyHost", "socksProxyPort"
+		}
+		host := props[hostKey]
+		if host == "" {
+			continue
+		}
+		if port := props[portKey]; port != "" {
+			host = host + ":" + port
+		}
+		var noProxy []string
+		if key != "socks" {
+			noProxy = splitNoProxy(props["http.nonProxyHosts"], "|")
+		}
+		if u := parseProxyURL(host, javaProxySchemes[key]); u != nil {
+			r.addProxy(fmt.Sprintf("%s (-D%s)", source, hostKey), u, noProxy)
+		}
+	}
+}
+
+// parseProxyURL normalises host:port style proxy values into a URL
+func parseProxyURL(value, scheme string) *url.URL {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	if !strings.Contains(value, "://") {
+		value = scheme + "://" + value
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return nil
+	}
+	return u
+}
+
+// redactProxyURL hides the credentials of a proxy URL, which would otherwise
+// end up in the public manifest
+func redactProxyURL(u *url.URL) string {
+	if u.User == nil {
+		return u.String()
+	}
+	redacted := *u
+	redacted.User = nil
+	return strings.Replace(redacted.String(), "://", "://***@", 1)
+}
+
+// redactProxy is redactProxyURL for raw proxy settings that may not parse
+func redactProxy(value string) string {
+	if u, err := url.Parse(strings.TrimSpace(value)); err == nil && u.Host != "" {
+		return redactProxyURL(u)
+	}
+	if at := strings.LastIndex(value, "@"); at >= 0 {
+		start := 0
+		if i := strings.Index(value, "://"); i >= 0 && i < at {
+			start = i + 3
+		}
+		return value[:start] + "***" + value[at:]
+	}
+	return value
+}
+
+// splitNoProxy splits a NO_PROXY or http.nonProxyHosts list into lowercase
+// host patterns
+func splitNoProxy(value, sep string) []string {
+	var patterns []string
+	for _, p := range strings.Split(value, sep) {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// bypassesProxy reports whether host matches one of the NO_PROXY style
+// patterns, so the app would connect to it directly. Patterns may also use
+// the wildcards of Java's http.nonProxyHosts and Windows' ProxyOverride, such
+// as "10.*", and "<local>" for hosts without a dot.
+func bypassesProxy(host string, patterns []string) bool {
+	host = strings.ToLower(host)
+	for _, p := range patterns {
+		if p == "*" {
+			return true
+		}
+		if p == "<local>" {
+			if !strings.Contains(host, ".") {
+				return true
+			}
+			continue
+		}
+		if h, _, err := net.SplitHostPort(p); err == nil {
+			p = h
+		}
+		if strings.Contains(strings.TrimPrefix(p, "*"), "*") {
+			if ok, _ := path.Match(p, host); ok {
+				return true
+			}
+			continue
+		}
+		p = strings.TrimPrefix(strings.TrimPrefix(p, "*"), ".")
+		if p != "" && (host == p || strings.HasSuffix(host, "."+p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyFunc routes requests through p, except for the hosts its source
+// excludes from proxying
+func (p DetectedProxy) proxyFunc() func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassesProxy(req.URL.Hostname(), p.noProxy) {
+			return nil, nil
+		}
+		return p.proxy, nil
+	}
+}
+
+// compareProxyChecks runs the network checks directly and through each
+// detected proxy and records the checks where the outcome differs
+func compareProxyChecks(report *ProxyReport, checks []CheckURLStruct) {
+	if len(report.Detected) == 0 {
+		return
+	}
+	direct := runChecks(checks, &http.Transport{Proxy: nil})
+	for i, p := range report.Detected {
+		proxied := runChecks(checks, &http.Transport{Proxy: p.proxyFunc()})
+		report.Detected[i].Total = len(direct)
+		for j := range direct {
+			if direct[j].Success {
+				report.Detected[i].DirectPassed++
+			}
+			if proxied[j].Success {
+				report.Detected[i].ProxyPassed++
+			}
+			if direct[j].Success != proxied[j].Success {
+				report.Detected[i].Differences = append(report.Detected[i].Differences, ProxyDifference{
+					URL:        direct[j].URL,
+					Direct:     direct[j].Status,
+					ViaProxy:   proxied[j].Status,
+					DirectOk:   direct[j].Success,
+					ViaProxyOk: proxied[j].Success,
+				})
+			}
+		}
+	}
+}
+
+func printProxyReport(report ProxyReport) {
+	if len(report.Environment) == 0 && len(report.System) == 0 && len(report.JavaProperties) == 0 && len(report.Detected) == 0 {
+		pterm.Info.Println("No proxy configuration detected")
+		return
+	}
+	for name, value := range report.Environment {
+		pterm.Info.Printfln("Environment %s=%s", name, value)
+	}
+	for _, s := range report.System {
+		pterm.Info.Println("System proxy", s)
+	}
+	for _, j := range report.JavaProperties {
+		pterm.Info.Println("Java proxy property", j)
+	}
+	for _, p := range report.Detected {
+		pterm.Info.Printfln("%s (from %s): %d/%d checks passed directly, %d/%d via proxy", p.URL, strings.Join(p.Sources, ", "), p.DirectPassed, p.Total, p.ProxyPassed, p.Total)
+		for _, d := range p.Differences {
+			if d.DirectOk {
+				pterm.Warning.Printfln("%s works directly but fails via proxy: %s", d.URL, d.ViaProxy)
+			} else {
+				pterm.Warning.Printfln("%s only works via proxy: %s", d.URL, d.Direct)
+			}
+		}
+	}
+}
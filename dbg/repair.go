@@ -0,0 +1,284 @@
+package dbg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// RunRepairSettings backs up and rewrites a corrupt settings.json after
+// showing the changes and asking for confirmation
+func RunRepairSettings(opts Options) {
+	options = opts
+	pterm.DefaultHeader.Println("Repair settings.json")
+
+	installLocation, err := locateFTBAFolder()
+	if err != nil {
+		pterm.Error.Println("Error locating app:", err)
+		return
+	}
+	ftbApp.InstallLocation = installLocation
+	settingsPath := filepath.Join(installLocation, "storage", "settings.json")
+	original, err := platform.FS.ReadFile(settingsPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		pterm.Error.Println("Failed to read settings.json:", err)
+		return
+	}
+
+	repaired, notes := repairSettings(original, installLocation)
+	for _, note := range notes {
+		pterm.Info.Println(note)
+	}
+	repairedJSON, err := json.MarshalIndent(repaired, "", "  ")
+	if err != nil {
+		pterm.Error.Println("Failed to encode repaired settings:", err)
+		return
+	}
+
+	changes := diffSettingsJSON(original, repairedJSON)
+	if len(changes) == 0 && json.Valid(original) {
+		pterm.Success.Println("settings.json is valid, nothing to repair")
+		return
+	}
+	pterm.DefaultSection.Println("Changes")
+	for _, c := range changes {
+		pterm.Println(c)
+	}
+
+	confirmed, _ := pterm.DefaultInteractiveConfirm.Show(fmt.Sprintf("Write the repaired settings to %s?", settingsPath))
+	if !confirmed {
+		pterm.Warning.Println("Nothing was changed")
+		return
+	}
+
+	if len(original) > 0 {
+		backupPath := fmt.Sprintf("%s.%s.bak", settingsPath, time.Now().Format("20060102-150405"))
+		if err := os.WriteFile(backupPath, original, 0644); err != nil {
+			pterm.Error.Println("Failed to back up settings.json, not writing repaired file:", err)
+			return
+		}
+		pterm.Info.Println("Backed up original settings to", backupPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(settingsPath), 0755); err != nil {
+		pterm.Error.Println("Failed to create storage directory:", err)
+		return
+	}
+	if err := os.WriteFile(settingsPath, repairedJSON, 0644); err != nil {
+		pterm.Error.Println("Failed to write settings.json:", err)
+		return
+	}
+	pterm.Success.Println("settings.json repaired")
+}
+
+// repairSettings recovers as much of data as possible, replacing values that
+// can't be parsed with the app defaults
+func repairSettings(data []byte, installLocation string) (AppSettings, []string) {
+	var notes []string
+	defaults := defaultAppSettings
+	defaults.InstanceLocation = filepath.Join(installLocation, "instances")
+
+	var settings AppSettings
+	recovered, note, err := recoverJSONObject(data)
+	if note != "" {
+		notes = append(notes, note)
+	}
+	if err == nil {
+		err = json.Unmarshal(recovered, &settings)
+	}
+	if err != nil {
+		notes = append(notes, fmt.Sprintf("Unable to recover any settings (%s), starting from defaults", err))
+		_ = json.Unmarshal([]byte("{}"), &settings)
+		t := reflect.TypeOf(settings)
+		for i := 0; i < t.NumField(); i++ {
+			if key := settingKey(t.Field(i)); key != "" {
+				resetSetting(&settings, key, defaults)
+			}
+		}
+	}
+
+	for _, problem := range settings.Problems {
+		key, _, _ := strings.Cut(problem, ":")
+		resetSetting(&settings, key, defaults)
+		notes = append(notes, fmt.Sprintf("Reset corrupt value %s", problem))
+	}
+	settings.Problems = nil
+
+	if err := checkInstanceLocation(settings.InstanceLocation); err != nil {
+		location := findInstanceLocation(installLocation)
+		resetSetting(&settings, "instanceLocation", AppSettings{InstanceLocation: location})
+		notes = append(notes, fmt.Sprintf("%s, using %s", err, location))
+	}
+	return settings, notes
+}
+
+// recoverJSONObject returns the first JSON object in data, dropping anything
+// after it and closing it off if the file was truncated
+func recoverJSONObject(data []byte) ([]byte, string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	data = bytes.Trim(data, "\x00 \t\r\n")
+	if len(data) == 0 {
+		return nil, "", errors.New("file is empty")
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var obj map[string]json.RawMessage
+	if err := dec.Decode(&obj); err == nil {
+		if rest := bytes.TrimSpace(data[dec.InputOffset():]); len(rest) > 0 {
+			return data[:dec.InputOffset()], fmt.Sprintf("Stripped %d bytes of trailing garbage", len(rest)), nil
+		}
+		return data, "", nil
+	}
+
+	for _, candidate := range truncatedCandidates(data) {
+		if json.Unmarshal(candidate, &obj) == nil {
+			return candidate, "Recovered settings from a truncated file", nil
+		}
+	}
+	return nil, "", errors.New("not a JSON object")
+}
+
+// truncatedCandidates returns data closed off as is, and data cut back to the
+// last complete top level member
+func truncatedCandidates(data []byte) [][]byte {
+	var stack []byte
+	inString, escaped := false, false
+	lastMember := -1
+	for i, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{':
+			stack = append(stack, '}')
+		case '[':
+			stack = append(stack, ']')
+		case '}', ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case ',':
+			if len(stack) == 1 {
+				lastMember = i
+			}
+		}
+	}
+
+	var candidates [][]byte
+	closed := bytes.Clone(data)
+	if inString {
+		closed = append(closed, '"')
+	}
+	closed = bytes.TrimRight(closed, ", \t\r\n")
+	for i := len(stack) - 1; i >= 0; i-- {
+		closed = append(closed, stack[i])
+	}
+	candidates = append(candidates, closed)
+	if lastMember > 0 && data[0] == '{' {
+		candidates = append(candidates, append(bytes.Clone(data[:lastMember]), '}'))
+	}
+	return candidates
+}
+
+// resetSetting copies the value of key from defaults into s
+func resetSetting(s *AppSettings, key string, defaults AppSettings) {
+	v, d := reflect.ValueOf(s).Elem(), reflect.ValueOf(defaults)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if settingKey(t.Field(i)) == key {
+			v.Field(i).Set(d.Field(i))
+			if s.quoted == nil {
+				s.quoted = make(map[string]bool)
+			}
+			s.quoted[key] = true
+			return
+		}
+	}
+}
+
+// findInstanceLocation looks for a directory containing instances in the
+// places the app has used by default
+func findInstanceLocation(installLocation string) string {
+	candidates := []string{
+		filepath.Join(installLocation, "instances"),
+		filepath.Join(platform.HomeDir, "FTBA", "instances"),
+		filepath.Join(platform.HomeDir, "Documents", "FTBA", "instances"),
+		filepath.Join(platform.LocalAppData, ".ftba", "instances"),
+	}
+	for _, candidate := range candidates {
+		entries, err := platform.FS.ReadDir(candidate)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.IsDir() && pathExists(filepath.Join(candidate, e.Name(), "instance.json")) {
+				return candidate
+			}
+		}
+	}
+	return candidates[0]
+}
+
+// diffSettingsJSON lists the top level keys that were added, removed or
+// changed between the original file and the repaired one
+func diffSettingsJSON(original, repaired []byte) []string {
+	var before, after map[string]json.RawMessage
+	if r, _, err := recoverJSONObject(original); err == nil {
+		_ = json.Unmarshal(r, &before)
+	}
+	_ = json.Unmarshal(repaired, &after)
+
+	keys := make(map[string]bool)
+	for k := range before {
+		keys[k] = true
+	}
+	for k := range after {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var changes []string
+	for _, k := range sorted {
+		b, inBefore := before[k]
+		a, inAfter := after[k]
+		switch {
+		case !inBefore:
+			changes = append(changes, pterm.Green(fmt.Sprintf("+ %s: %s", k, a)))
+		case !inAfter:
+			changes = append(changes, pterm.Red(fmt.Sprintf("- %s: %s", k, b)))
+		case !bytes.Equal(compactJSON(b), compactJSON(a)):
+			changes = append(changes, pterm.Yellow(fmt.Sprintf("~ %s: %s -> %s", k, b, a)))
+		}
+	}
+	return changes
+}
+
+func compactJSON(data []byte) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return data
+	}
+	return buf.Bytes()
+}
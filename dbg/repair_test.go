@@ -0,0 +1,121 @@
+package dbg
+
+import (
+	"encoding/json"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRecoverJSONObject(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+		note string
+	}{
+		{"valid", `{"a": "1", "b": [1, 2]}`, `{"a": "1", "b": [1, 2]}`, ""},
+		{"trailing garbage", "{\"a\": \"1\"}\n}garbage", `{"a": "1"}`, "Stripped 8 bytes of trailing garbage"},
+		{"second object", `{"a": "1"}{"b": "2"}`, `{"a": "1"}`, "Stripped 10 bytes of trailing garbage"},
+		{"BOM and NUL padding", "\xef\xbb\xbf{\"a\": \"1\"}\x00\x00\x00", `{"a": "1"}`, ""},
+		{"truncated in a string", `{"a": "1", "b": "hel`, `{"a": "1", "b": "hel"}`, "Recovered settings from a truncated file"},
+		{"truncated in an escape", `{"a": "1", "b": "C:\`, `{"a": "1"}`, "Recovered settings from a truncated file"},
+		{"truncated in a key", `{"a": "1", "bl`, `{"a": "1"}`, "Recovered settings from a truncated file"},
+		{"truncated after a colon", `{"a": "1", "b":`, `{"a": "1"}`, "Recovered settings from a truncated file"},
+		{"truncated after a comma", "{\"a\": \"1\",\n", `{"a": "1"}`, "Recovered settings from a truncated file"},
+		{"truncated in a nested value", `{"a": "1", "b": {"c": [1, 2`, `{"a": "1", "b": {"c": [1, 2]}}`, "Recovered settings from a truncated file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, note, err := recoverJSONObject([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want || note != tt.note {
+				t.Errorf("got %s (%q), want %s (%q)", got, note, tt.want, tt.note)
+			}
+		})
+	}
+
+	for _, data := range []string{"", " \r\n\t", "\x00\x00", "\xef\xbb\xbf", "not json", `["a"]`} {
+		if got, _, err := recoverJSONObject([]byte(data)); err == nil {
+			t.Errorf("recoverJSONObject(%q) = %s, want an error", data, got)
+		}
+	}
+}
+
+func TestTruncatedCandidates(t *testing.T) {
+	tests := []struct {
+		data string
+		want []string
+	}{
+		{`{"a": "1", "b": "x`, []string{`{"a": "1", "b": "x"}`, `{"a": "1"}`}},
+		{`{"a": "}", "b": [`, []string{`{"a": "}", "b": []}`, `{"a": "}"}`}},
+		{`{"a": "1"`, []string{`{"a": "1"}`}},
+		{`{"a": {"b": 1, "c": 2`, []string{`{"a": {"b": 1, "c": 2}}`}},
+	}
+	for _, tt := range tests {
+		got := truncatedCandidates([]byte(tt.data))
+		var gotStrings []string
+		for _, c := range got {
+			gotStrings = append(gotStrings, string(c))
+		}
+		if strings.Join(gotStrings, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("truncatedCandidates(%s) = %q, want %q", tt.data, gotStrings, tt.want)
+		}
+	}
+}
+
+func TestRepairSettings(t *testing.T) {
+	files := fstest.MapFS{
+		"ftba/instances":                          {Mode: fs.ModeDir | 0755},
+		"games/instances/pack/instance.json":      {Data: []byte("{}")},
+		"home/FTBA/instances/pack/instance.json":  {Data: []byte("{}")},
+		"home/Documents/FTBA/instances/readme.md": {Data: []byte("not an instance")},
+	}
+	install := fixturePath("ftba")
+
+	t.Run("valid file is unchanged", func(t *testing.T) {
+		useFixture(t, files, Options{})
+		original := []byte(`{"instanceLocation": "` + jsonEscape(fixturePath("games", "instances")) + `", "memory": "4096", "enableChat": true, "newAppKey": [1, 2]}`)
+		repaired, notes := repairSettings(original, install)
+		data, err := json.Marshal(repaired)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changes := diffSettingsJSON(original, data); len(changes) > 0 || len(notes) > 0 {
+			t.Errorf("changes %q, notes %q", changes, notes)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		home     string
+		settings string
+		want     string
+	}{
+		{"missing location falls back to a directory with instances", fixturePath("home"), `{"memory": "4096"}`, fixturePath("home", "FTBA", "instances")},
+		{"unusable location falls back to a directory with instances", fixturePath("home"), `{"instanceLocation": "/nowhere"}`, fixturePath("home", "FTBA", "instances")},
+		{"no instances anywhere falls back to the install location", fixturePath("empty"), `{"instanceLocation": "/nowhere"}`, fixturePath("ftba", "instances")},
+		{"unrecoverable file starts from the default location", fixturePath("home"), "", fixturePath("ftba", "instances")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFixture(t, files, Options{})
+			platform.HomeDir, platform.LocalAppData = tt.home, fixturePath("empty")
+			repaired, notes := repairSettings([]byte(tt.settings), install)
+			if repaired.InstanceLocation != tt.want {
+				t.Errorf("instanceLocation = %s, want %s", repaired.InstanceLocation, tt.want)
+			}
+			if len(notes) == 0 {
+				t.Error("no note explains the change")
+			}
+		})
+	}
+}
+
+func jsonEscape(s string) string {
+	b, _ := json.Marshal(s)
+	return strings.Trim(string(b), `"`)
+}
@@ -0,0 +1,102 @@
+package dbg
+
+import (
+	_ "embed"
+	"html/template"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"ftb-debug/v2/shared"
+)
+
+//go:embed report.html.tmpl
+var reportTemplate string
+
+type (
+	reportData struct {
+		Manifest  Manifest
+		Code      string
+		Generated time.Time
+		Version   string
+		Instances []reportInstance
+		Orphans   []reportInstance
+	}
+	reportInstance struct {
+		Instances
+		Logs *InstanceLogs
+	}
+)
+
+var reportFuncs = template.FuncMap{
+	"bytes": ByteCountIEC,
+	// date accepts unix seconds or milliseconds, instance.json uses the latter
+	"date": func(v any) string {
+		var unix int64
+		switch t := v.(type) {
+		case int:
+			unix = int64(t)
+		case int64:
+			unix = t
+		}
+		if unix <= 0 {
+			return "never"
+		}
+		return epochTime(unix).Format(time.DateTime)
+	},
+	"checkClass": func(n NetworkCheck) string {
+		if n.Success {
+			return "ok"
+		}
+		if n.Error && n.Severity != "warning" && n.Severity != "info" {
+			return "fail"
+		}
+		return "warn"
+	},
+}
+
+// writeHTMLReport renders the manifest as a single page with no external
+// resources so it can be opened offline or attached to a ticket
+func writeHTMLReport(path string, manifest Manifest, code string) error {
+	tmpl, err := template.New("report").Funcs(reportFuncs).Funcs(template.FuncMap{"paste": manifest.logLink, "where": manifest.refLocation}).Parse(reportTemplate)
+	if err != nil {
+		return err
+	}
+
+	data := reportData{
+		Manifest:  manifest,
+		Code:      code,
+		Generated: time.Now(),
+		Version:   shared.Version,
+	}
+	for _, i := range manifest.ProviderInstanceMapping {
+		ri := reportInstance{Instances: i}
+		for n := range manifest.InstanceLogs {
+			if manifest.InstanceLogs[n].UUID == i.UUID {
+				ri.Logs = &manifest.InstanceLogs[n]
+				break
+			}
+		}
+		if i.Orphaned {
+			data.Orphans = append(data.Orphans, ri)
+		} else {
+			data.Instances = append(data.Instances, ri)
+		}
+	}
+	byName := func(a, b reportInstance) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	}
+	slices.SortFunc(data.Instances, byName)
+	slices.SortFunc(data.Orphans, byName)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := tmpl.Execute(f, data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,304 @@
+package dbg
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"ftb-debug/v2/shared"
+
+	"github.com/pterm/pterm"
+)
+
+// manifestSchemaVersion is bumped whenever a manifest field is renamed, moved
+// or changes type. Adding optional fields does not need a bump. Every bump
+// needs a matching entry in manifestMigrations so old manifests still load,
+// and manifest.schema.json has to be regenerated, see schema_test.go.
+//
+//	1: v2.0.5-go and earlier
+//	2: schemaVersion added, existing keys unchanged
+//	3: log maps may reference inlineFiles and batches as well as paste ids
+//
+// manifestSchemaVersion is only written when the manifest uses inline files
+// or batches. Every other manifest is written as pasteIDSchemaVersion so
+// consumers that only understand paste ids keep reading it.
+const (
+	manifestSchemaVersion = 3
+	pasteIDSchemaVersion  = 2
+)
+
+// manifestMigrations upgrade a decoded manifest from the version in the key
+// to the next version. None are needed yet, versions 2 and 3 only added
+// fields and version 2 paste ids are valid version 3 references.
+var manifestMigrations = map[int]func(map[string]any){}
+
+// publishedSchema is the schema support tooling pins against. Manifests are
+// validated against it rather than the Go types so a renamed, removed or
+// retyped field that is not reflected in the published schema is caught
+// before upload.
+//
+//go:embed manifest.schema.json
+var publishedSchema []byte
+
+// toolVersion is the version written into manifests
+func toolVersion() string {
+	v := shared.Version
+	if !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+	return v + "-go"
+}
+
+// RunSchema writes the JSON Schema of the manifest to path, which defaults to
+// manifest.schema.json in the working directory
+func RunSchema(opts Options, path string) {
+	options = opts
+	data, err := json.MarshalIndent(manifestSchema(), "", "  ")
+	if err != nil {
+		pterm.Error.Println("Failed to generate schema:", err)
+		return
+	}
+	if path == "" {
+		path = "manifest.schema.json"
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		pterm.Error.Println("Failed to write schema:", err)
+		return
+	}
+	pterm.Success.Println("Schema written to", path)
+}
+
+// manifestSchema generates a JSON Schema for Manifest from its Go type. Every
+// struct gets a $defs entry named after the type.
+func manifestSchema() map[string]any {
+	defs := make(map[string]any)
+	root := typeSchema(reflect.TypeOf(Manifest{}), defs)
+	return map[string]any{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"title":       "FTB debug manifest",
+		"description": fmt.Sprintf("FTB debug tool manifest, schema version %d", manifestSchemaVersion),
+		"$ref":        root["$ref"],
+		"$defs":       defs,
+	}
+}
+
+func typeSchema(t reflect.Type, defs map[string]any) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem(), defs)
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem(), defs)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem(), defs)}
+	case reflect.Struct:
+		ref := map[string]any{"$ref": "#/$defs/" + t.Name()}
+		if _, ok := defs[t.Name()]; ok {
+			return ref
+		}
+		// Placeholder so self referencing types terminate
+		defs[t.Name()] = nil
+
+		properties := make(map[string]any)
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, omitEmpty, ok := jsonField(f)
+			if !ok {
+				continue
+			}
+			fs := typeSchema(f.Type, defs)
+			if !omitEmpty {
+				required = append(required, name)
+				if k := f.Type.Kind(); k == reflect.Pointer || k == reflect.Slice || k == reflect.Map {
+					fs = map[string]any{"anyOf": []any{fs, map[string]any{"type": "null"}}}
+				}
+			}
+			properties[name] = fs
+		}
+		def := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			slices.Sort(required)
+			def["required"] = required
+		}
+		defs[t.Name()] = def
+		return ref
+	default:
+		// interface{} and anything else can hold any JSON value
+		return map[string]any{}
+	}
+}
+
+// jsonField returns the key encoding/json uses for a struct field
+func jsonField(f reflect.StructField) (name string, omitEmpty, ok bool) {
+	if !f.IsExported() {
+		return "", false, false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = f.Name
+	}
+	return name, slices.Contains(strings.Split(opts, ","), "omitempty"), true
+}
+
+// validateManifest checks the encoded manifest against the published schema
+// and returns a message for every violation
+func validateManifest(data []byte) []string {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return []string{err.Error()}
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(publishedSchema, &schema); err != nil {
+		return []string{"published schema: " + err.Error()}
+	}
+	defs, _ := schema["$defs"].(map[string]any)
+	return validateValue(v, schema, defs, "$")
+}
+
+func validateValue(v any, schema map[string]any, defs map[string]any, path string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		def, _ := defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+		return validateValue(v, def, defs, path)
+	}
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		var errs []string
+		for _, s := range anyOf {
+			e := validateValue(v, s.(map[string]any), defs, path)
+			if len(e) == 0 {
+				return nil
+			}
+			errs = append(errs, e...)
+		}
+		return errs
+	}
+
+	want, _ := schema["type"].(string)
+	switch want {
+	case "":
+		return nil
+	case "null":
+		if v != nil {
+			return []string{fmt.Sprintf("%s: expected null", path)}
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return []string{fmt.Sprintf("%s: expected boolean", path)}
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return []string{fmt.Sprintf("%s: expected string", path)}
+		}
+	case "integer", "number":
+		n, ok := v.(json.Number)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected %s", path, want)}
+		}
+		if want == "integer" && strings.ContainsAny(string(n), ".eE") {
+			return []string{fmt.Sprintf("%s: expected integer, got %s", path, n)}
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected array", path)}
+		}
+		items, _ := schema["items"].(map[string]any)
+		var errs []string
+		for i, item := range arr {
+			errs = append(errs, validateValue(item, items, defs, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return errs
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected object", path)}
+		}
+		var errs []string
+		required, _ := schema["required"].([]any)
+		for _, key := range required {
+			if _, ok := obj[key.(string)]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: missing %q", path, key))
+			}
+		}
+		// Like the published schema, keys it doesn't list are allowed so
+		// manifests with newer optional fields still validate
+		properties, _ := schema["properties"].(map[string]any)
+		additional, _ := schema["additionalProperties"].(map[string]any)
+		for _, key := range slices.Sorted(maps.Keys(obj)) {
+			s, ok := properties[key].(map[string]any)
+			if !ok {
+				s = additional
+			}
+			if s == nil {
+				continue
+			}
+			errs = append(errs, validateValue(obj[key], s, defs, path+"."+key)...)
+		}
+		return errs
+	}
+	return nil
+}
+
+// schemaVersion is the oldest schema version that can describe m
+func (m Manifest) schemaVersion() int {
+	if len(m.InlineFiles) > 0 || len(m.Batches) > 0 {
+		return manifestSchemaVersion
+	}
+	return pasteIDSchemaVersion
+}
+
+// readManifest decodes a manifest of any schema version, migrating older
+// layouts to the current one
+func readManifest(data []byte) (Manifest, error) {
+	var manifest Manifest
+	// Numbers are kept as json.Number so large ids survive the round trip
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw map[string]any
+	if err := dec.Decode(&raw); err != nil {
+		return manifest, err
+	}
+
+	version := 1
+	if v, ok := raw["schemaVersion"].(json.Number); ok {
+		if n, err := v.Int64(); err == nil {
+			version = int(n)
+		}
+	}
+	if version > manifestSchemaVersion {
+		pterm.Warning.Printfln("Manifest schema version %d is newer than this tool supports (%d), some fields may be missing", version, manifestSchemaVersion)
+	}
+	for ; version < manifestSchemaVersion; version++ {
+		if migrate, ok := manifestMigrations[version]; ok {
+			migrate(raw)
+		}
+	}
+	raw["schemaVersion"] = version
+
+	upgraded, err := json.Marshal(raw)
+	if err != nil {
+		return manifest, err
+	}
+	err = json.Unmarshal(upgraded, &manifest)
+	return manifest, err
+}
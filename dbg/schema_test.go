@@ -0,0 +1,147 @@
+package dbg
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+)
+
+var updateSchema = flag.Bool("update", false, "rewrite manifest.schema.json from the Go types")
+
+// The published schema has to match the Go types, run
+// go test ./dbg -run TestPublishedSchema -update after changing the manifest
+func TestPublishedSchema(t *testing.T) {
+	generated, err := json.MarshalIndent(manifestSchema(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated = append(generated, '\n')
+	if *updateSchema {
+		if err := os.WriteFile("manifest.schema.json", generated, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if !bytes.Equal(generated, publishedSchema) {
+		t.Fatal("manifest.schema.json is out of date, run go test ./dbg -run TestPublishedSchema -update")
+	}
+}
+
+func TestValidateManifest(t *testing.T) {
+	manifest := Manifest{
+		SchemaVersion: pasteIDSchemaVersion,
+		Version:       "v2.1.0-go",
+		AppLogs:       map[string]string{"latest.log": "abc123"},
+		NetworkChecks: []NetworkCheck{{URL: "https://api.feed-the-beast.com", Success: true, Status: "200 OK"}},
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := validateManifest(data); len(problems) > 0 {
+		t.Fatalf("valid manifest reported problems: %v", problems)
+	}
+
+	tests := []struct {
+		name     string
+		manifest string
+	}{
+		{"missing required key", `{"version":"v2.1.0-go","metaDetails":{},"appDetails":{"meta":{}}}`},
+		{"wrong type", `{"schemaVersion":"2","version":"v2.1.0-go","metaDetails":{},"appDetails":{"meta":{}}}`},
+		{"renamed network check key", `{"schemaVersion":2,"version":"v2.1.0-go","metaDetails":{},"appDetails":{"meta":{}},"networkChecks":[{"url":"x","Success":true,"Error":false,"Status":""}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if problems := validateManifest([]byte(tt.manifest)); len(problems) == 0 {
+				t.Error("expected problems")
+			}
+		})
+	}
+}
+
+func TestReadManifestV1(t *testing.T) {
+	v1 := `{"version":"v2.0.5-go","metaDetails":{"instanceCount":1},"networkChecks":[{"URL":"https://api.feed-the-beast.com","Success":true,"Error":false,"Status":"200 OK"}]}`
+	m, err := readManifest([]byte(v1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.SchemaVersion != manifestSchemaVersion {
+		t.Errorf("schema version %d, want %d", m.SchemaVersion, manifestSchemaVersion)
+	}
+	if len(m.NetworkChecks) != 1 || m.NetworkChecks[0].URL != "https://api.feed-the-beast.com" || !m.NetworkChecks[0].Success {
+		t.Errorf("network checks not read: %+v", m.NetworkChecks)
+	}
+}
+
+// The validator accepts exactly what the schema the schema command writes
+// accepts, including keys added by newer versions of the tool
+func TestValidatorMatchesSchema(t *testing.T) {
+	data, err := json.Marshal(manifestSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	defs := schema["$defs"].(map[string]any)
+	for name, def := range defs {
+		if additional, ok := def.(map[string]any)["additionalProperties"]; ok && additional == false {
+			t.Errorf("%s forbids additional properties but the validator allows them", name)
+		}
+	}
+
+	base, err := json.Marshal(Manifest{SchemaVersion: pasteIDSchemaVersion, Version: "v2.1.0-go", NetworkChecks: []NetworkCheck{{URL: "x"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		edit  func(m map[string]any)
+		valid bool
+	}{
+		{"current manifest", func(m map[string]any) {}, true},
+		{"newer top level key", func(m map[string]any) { m["newSection"] = map[string]any{"a": 1} }, true},
+		{"newer nested key", func(m map[string]any) {
+			m["metaDetails"].(map[string]any)["newCount"] = 1
+			m["networkChecks"].([]any)[0].(map[string]any)["newField"] = "y"
+		}, true},
+		{"missing required key", func(m map[string]any) { delete(m, "schemaVersion") }, false},
+		{"wrong type", func(m map[string]any) { m["schemaVersion"] = "2" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m map[string]any
+			if err := json.Unmarshal(base, &m); err != nil {
+				t.Fatal(err)
+			}
+			tt.edit(m)
+			manifest, err := json.Marshal(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dec := json.NewDecoder(bytes.NewReader(manifest))
+			dec.UseNumber()
+			var v any
+			if err := dec.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+			generated := validateValue(v, schema, defs, "$")
+			published := validateManifest(manifest)
+			if (len(generated) == 0) != tt.valid || (len(published) == 0) != tt.valid {
+				t.Errorf("generated schema: %v, published schema: %v, want valid %t", generated, published, tt.valid)
+			}
+		})
+	}
+}
+
+func TestManifestSchemaVersion(t *testing.T) {
+	if v := (Manifest{AppLogs: map[string]string{"latest.log": "abc123"}}).schemaVersion(); v != pasteIDSchemaVersion {
+		t.Errorf("paste id manifest written as version %d", v)
+	}
+	if v := (Manifest{InlineFiles: []InlineFile{{Name: "latest.log"}}}).schemaVersion(); v != manifestSchemaVersion {
+		t.Errorf("manifest with inline files written as version %d", v)
+	}
+}
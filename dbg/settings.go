@@ -0,0 +1,276 @@
+package dbg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pterm/pterm"
+)
+
+// defaultAppSettings are the values repair-settings writes for keys it can't
+// recover. They were chosen as safe values, not copied from the app, so only
+// the keys in confirmedDefaults are compared against them in the settings
+// diff. InstanceLocation is filled in relative to the install location.
+var defaultAppSettings = AppSettings{
+	EnableAnalytics:  true,
+	Memory:           4096,
+	ListMode:         "false",
+	ShowAdverts:      true,
+	AutoOpenChat:     true,
+	PackCardSize:     5,
+	ThreadLimit:      2,
+	Height:           720,
+	CacheLife:        5184000,
+	EnableChat:       true,
+	KeepLauncherOpen: true,
+	Width:            1280,
+	ExitOverwolf:     false,
+	BlockedUsers:     "[]",
+}
+
+// confirmedDefaults are the settings whose app default is known. The app
+// creates instances in <install location>/instances unless told otherwise,
+// which is also where getInstances looks by default.
+var confirmedDefaults = []string{"instanceLocation"}
+
+// UnmarshalJSON accepts both the stringly typed values older app versions
+// write ("4096", "true") and native JSON types. Values that can't be
+// converted are recorded as problems instead of failing the whole file, and
+// keys we don't know about are kept so the file can be written back intact.
+func (s *AppSettings) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*s = AppSettings{Extra: make(map[string]json.RawMessage), quoted: make(map[string]bool)}
+
+	v := reflect.ValueOf(s).Elem()
+	t := v.Type()
+	known := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		key := settingKey(t.Field(i))
+		if key == "" {
+			continue
+		}
+		known[key] = true
+		value, ok := raw[key]
+		if !ok {
+			continue
+		}
+		s.quoted[key] = bytes.HasPrefix(bytes.TrimSpace(value), []byte(`"`))
+		if err := setSettingField(v.Field(i), value); err != nil {
+			s.Problems = append(s.Problems, fmt.Sprintf("%s: %s", key, err))
+		}
+	}
+	for key, value := range raw {
+		if !known[key] {
+			s.Extra[key] = value
+		}
+	}
+	return nil
+}
+
+// MarshalJSON writes the settings back in the same form they were read
+func (s AppSettings) MarshalJSON() ([]byte, error) {
+	out := make(map[string]json.RawMessage)
+	for key, value := range s.Extra {
+		out[key] = value
+	}
+	v := reflect.ValueOf(s)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := settingKey(t.Field(i))
+		if key == "" || !s.has(key) && v.Field(i).IsZero() {
+			continue
+		}
+		var value string
+		switch f := v.Field(i); f.Kind() {
+		case reflect.Bool:
+			value = strconv.FormatBool(f.Bool())
+		case reflect.Int:
+			value = strconv.FormatInt(f.Int(), 10)
+		default:
+			value = f.String()
+		}
+		// The app stores everything as strings, only keep values unquoted when
+		// they were unquoted in the file we read
+		if quoted, ok := s.quoted[key]; ok && !quoted && json.Valid([]byte(value)) {
+			out[key] = json.RawMessage(value)
+			continue
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		out[key] = b
+	}
+	return json.Marshal(out)
+}
+
+// has reports whether key was present in the file the settings were read from
+func (s AppSettings) has(key string) bool {
+	_, ok := s.quoted[key]
+	return ok
+}
+
+// usable reports whether key was present and its value could be converted,
+// which is when its range is worth checking
+func (s AppSettings) usable(key string) bool {
+	if !s.has(key) {
+		return false
+	}
+	for _, problem := range s.Problems {
+		if strings.HasPrefix(problem, key+":") {
+			return false
+		}
+	}
+	return true
+}
+
+func settingKey(f reflect.StructField) string {
+	tag := f.Tag.Get("json")
+	if tag == "" || tag == "-" || !f.IsExported() {
+		return ""
+	}
+	return strings.Split(tag, ",")[0]
+}
+
+func setSettingField(f reflect.Value, value json.RawMessage) error {
+	var str string
+	isString := json.Unmarshal(value, &str) == nil
+	switch f.Kind() {
+	case reflect.Bool:
+		if !isString {
+			str = string(value)
+		}
+		b, err := strconv.ParseBool(strings.TrimSpace(str))
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %s", value)
+		}
+		f.SetBool(b)
+	case reflect.Int:
+		if !isString {
+			str = string(value)
+		}
+		str = strings.TrimSpace(str)
+		if str == "" {
+			return nil
+		}
+		n, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number, got %s", value)
+		}
+		if n != math.Trunc(n) || math.Abs(n) >= 1<<63 {
+			return fmt.Errorf("expected a whole number, got %s", value)
+		}
+		f.SetInt(int64(n))
+	case reflect.String:
+		if !isString {
+			str = string(value)
+		}
+		f.SetString(str)
+	}
+	return nil
+}
+
+// validateAppSettings checks the typed settings are within the ranges the app
+// accepts. totalMemory is the system memory in MB, 0 if unknown. Settings
+// missing from the file are left to the app's defaults and not checked, nor
+// are values already reported as unconvertible.
+func validateAppSettings(s AppSettings, totalMemory int) []string {
+	problems := slices.Clone(s.Problems)
+	if s.usable("memory") {
+		if s.Memory < 512 {
+			problems = append(problems, fmt.Sprintf("memory: %d MB is below the 512 MB minimum", s.Memory))
+		} else if totalMemory > 0 && s.Memory > totalMemory {
+			problems = append(problems, fmt.Sprintf("memory: %d MB is more than the %d MB installed", s.Memory, totalMemory))
+		}
+	}
+	if s.usable("threadLimit") && (s.ThreadLimit < 1 || s.ThreadLimit > 64) {
+		problems = append(problems, fmt.Sprintf("threadLimit: %d is outside 1-64", s.ThreadLimit))
+	}
+	if s.usable("width") && (s.Width < 100 || s.Width > 16384) {
+		problems = append(problems, fmt.Sprintf("width: %d is outside 100-16384", s.Width))
+	}
+	if s.usable("height") && (s.Height < 100 || s.Height > 16384) {
+		problems = append(problems, fmt.Sprintf("height: %d is outside 100-16384", s.Height))
+	}
+	if s.usable("speedLimit") && s.SpeedLimit < 0 {
+		problems = append(problems, fmt.Sprintf("speedLimit: %d can't be negative", s.SpeedLimit))
+	}
+	if s.usable("cacheLife") && s.CacheLife < 0 {
+		problems = append(problems, fmt.Sprintf("cacheLife: %d can't be negative", s.CacheLife))
+	}
+	if err := checkInstanceLocation(s.InstanceLocation); err != nil {
+		problems = append(problems, err.Error())
+	}
+	return problems
+}
+
+// checkInstanceLocation explains why the configured instance location can't
+// be used
+func checkInstanceLocation(location string) error {
+	if strings.TrimSpace(location) == "" {
+		return errors.New("instanceLocation is not set in settings.json")
+	}
+	info, err := platform.FS.Stat(location)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("instanceLocation %q from settings.json does not exist", location)
+		}
+		return fmt.Errorf("instanceLocation %q from settings.json can't be read: %w", location, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("instanceLocation %q from settings.json is not a directory", location)
+	}
+	return nil
+}
+
+// diffAppSettings lists the settings that differ from their confirmed app
+// defaults and the keys this tool doesn't know
+func diffAppSettings(s AppSettings, defaults AppSettings) []SettingDiff {
+	var diffs []SettingDiff
+	v, d := reflect.ValueOf(s), reflect.ValueOf(defaults)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := settingKey(t.Field(i))
+		if !slices.Contains(confirmedDefaults, key) || !s.has(key) {
+			continue
+		}
+		if !v.Field(i).Equal(d.Field(i)) {
+			diffs = append(diffs, SettingDiff{Key: key, Default: fmt.Sprint(d.Field(i)), Value: fmt.Sprint(v.Field(i))})
+		}
+	}
+	keys := make([]string, 0, len(s.Extra))
+	for key := range s.Extra {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		diffs = append(diffs, SettingDiff{Key: key, Value: string(s.Extra[key]), Unknown: true})
+	}
+	return diffs
+}
+
+func printSettingsDiff(diffs []SettingDiff) {
+	if len(diffs) == 0 {
+		pterm.Info.Println("No settings differ from their known defaults")
+		return
+	}
+	for _, d := range diffs {
+		if d.Unknown {
+			pterm.Info.Printfln("%s: %s (unknown setting, kept as is)", d.Key, d.Value)
+		} else {
+			pterm.Info.Printfln("%s: %q (default %q)", d.Key, d.Value, d.Default)
+		}
+	}
+}
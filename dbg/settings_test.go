@@ -0,0 +1,60 @@
+package dbg
+
+import (
+	"encoding/json"
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestValidateAppSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+		want     []string
+	}{
+		{"missing keys use the defaults", `{"instanceLocation": "/games/instances"}`, nil},
+		{"stringly typed values", `{"instanceLocation": "/games/instances", "memory": "4096", "threadLimit": "2"}`, nil},
+		{"too little memory", `{"instanceLocation": "/games/instances", "memory": "256"}`,
+			[]string{"memory: 256 MB is below the 512 MB minimum"}},
+		{"more memory than installed", `{"instanceLocation": "/games/instances", "memory": 16384}`,
+			[]string{"memory: 16384 MB is more than the 8192 MB installed"}},
+		{"thread limit out of range", `{"instanceLocation": "/games/instances", "threadLimit": "0"}`,
+			[]string{"threadLimit: 0 is outside 1-64"}},
+		{"fractional number", `{"instanceLocation": "/games/instances", "memory": "4096.5", "width": 1280.0}`,
+			[]string{`memory: expected a whole number, got "4096.5"`}},
+		{"explicit zero is checked like any other value", `{"instanceLocation": "/games/instances", "width": 0, "height": "0", "speedLimit": 0}`,
+			[]string{"width: 0 is outside 100-16384", "height: 0 is outside 100-16384"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFixture(t, fstest.MapFS{"games/instances": {Mode: fs.ModeDir | 0755}}, Options{})
+			var s AppSettings
+			if err := json.Unmarshal([]byte(tt.settings), &s); err != nil {
+				t.Fatal(err)
+			}
+			if got := validateAppSettings(s, 8192); !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffAppSettings(t *testing.T) {
+	var s AppSettings
+	if err := json.Unmarshal([]byte(`{"instanceLocation": "/games/instances", "memory": "2048", "newAppKey": true}`), &s); err != nil {
+		t.Fatal(err)
+	}
+	defaults := defaultAppSettings
+	defaults.InstanceLocation = "/ftba/instances"
+
+	// memory has no confirmed default so it isn't compared
+	want := []SettingDiff{
+		{Key: "instanceLocation", Value: "/games/instances", Default: "/ftba/instances"},
+		{Key: "newAppKey", Value: "true", Unknown: true},
+	}
+	if got := diffAppSettings(s, defaults); !slices.Equal(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
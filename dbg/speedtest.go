@@ -0,0 +1,183 @@
+package dbg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+const (
+	mojangVersionManifest = "https://piston-meta.mojang.com/mc/game/version_manifest_v2.json"
+	// The pack list the app browses, served by the same API as pack installs
+	ftbModpackList    = "https://api.feed-the-beast.com/v1/modpacks/public/modpack/all"
+	adoptiumBinaryURL = "https://api.adoptium.net/v3/binary/latest/21/ga/%s/%s/jre/hotspot/normal/eclipse"
+	stallThreshold    = time.Second
+	slowDownloadSpeed = 1024 * 1024
+)
+
+// speedTestTargets returns large objects on the CDNs the app downloads packs
+// and runtimes from
+func speedTestTargets(extra []string) []SpeedTestTarget {
+	targets := []SpeedTestTarget{{Name: "FTB API", URL: ftbModpackList}}
+	if clientJar, err := latestClientJar(); err != nil {
+		pterm.Debug.Println("Unable to find Minecraft client jar for speed test:", err)
+	} else {
+		targets = append(targets, SpeedTestTarget{Name: "Mojang CDN", URL: clientJar})
+	}
+	adoptiumOS := map[string]string{"windows": "windows", "darwin": "mac", "linux": "linux"}[platform.GOOS]
+	adoptiumArch := map[string]string{"amd64": "x64", "arm64": "aarch64"}[platform.GOARCH]
+	if adoptiumOS != "" && adoptiumArch != "" {
+		targets = append(targets, SpeedTestTarget{Name: "Java runtime CDN", URL: fmt.Sprintf(adoptiumBinaryURL, adoptiumOS, adoptiumArch)})
+	}
+	for _, u := range extra {
+		targets = append(targets, SpeedTestTarget{Name: u, URL: u})
+	}
+	return targets
+}
+
+func latestClientJar() (string, error) {
+	var manifest struct {
+		Latest struct {
+			Release string `json:"release"`
+		} `json:"latest"`
+		Versions []struct {
+			ID  string `json:"id"`
+			URL string `json:"url"`
+		} `json:"versions"`
+	}
+	if err := getJSON(mojangVersionManifest, &manifest); err != nil {
+		return "", err
+	}
+	for _, v := range manifest.Versions {
+		if v.ID != manifest.Latest.Release {
+			continue
+		}
+		var version struct {
+			Downloads struct {
+				Client struct {
+					URL string `json:"url"`
+				} `json:"client"`
+			} `json:"downloads"`
+		}
+		if err := getJSON(v.URL, &version); err != nil {
+			return "", err
+		}
+		return version.Downloads.Client.URL, nil
+	}
+	return "", errors.New("latest release not found in version manifest")
+}
+
+func getJSON(url string, v any) error {
+	client := &http.Client{Timeout: defaultCheckTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// runSpeedTest downloads from target for at most duration and measures the
+// sustained throughput and the number of reads that stalled. Throughput is
+// timed from the response headers, the time to get them is reported as the
+// latency so DNS, TLS and server delays don't count against the connection.
+func runSpeedTest(target SpeedTestTarget, duration time.Duration) SpeedTestResult {
+	result := SpeedTestResult{Name: target.Name, URL: target.URL}
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.URL, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	requested := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+	start := time.Now()
+	result.Latency = start.Sub(requested).Milliseconds()
+	if resp.StatusCode != http.StatusOK {
+		result.Error = resp.Status
+		return result
+	}
+	result.Size = resp.ContentLength
+
+	buf := make([]byte, 32*1024)
+	last := time.Now()
+	for {
+		n, err := resp.Body.Read(buf)
+		now := time.Now()
+		if now.Sub(last) >= stallThreshold {
+			result.Stalls++
+		}
+		last = now
+		result.Bytes += int64(n)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, context.DeadlineExceeded) {
+				result.Error = err.Error()
+			}
+			break
+		}
+	}
+	elapsed := time.Since(start)
+	result.Duration = elapsed.Milliseconds()
+	if elapsed > 0 {
+		result.BytesPerSecond = int64(float64(result.Bytes) / elapsed.Seconds())
+	}
+	return result
+}
+
+// speedTestRecommendations compares the measured speeds with the app's
+// download settings. speedLimit is in KB/s with 0 meaning unlimited.
+func speedTestRecommendations(results []SpeedTestResult, settings AppSettings) []string {
+	var recs []string
+	var fastest int64
+	for _, r := range results {
+		if r.Error != "" && r.Bytes == 0 {
+			continue
+		}
+		fastest = max(fastest, r.BytesPerSecond)
+		if r.BytesPerSecond < slowDownloadSpeed {
+			recs = append(recs, fmt.Sprintf("Downloads from the %s only reached %s/s, slow pack installs are likely caused by the connection", r.Name, ByteCountIEC(r.BytesPerSecond)))
+		}
+		if r.Stalls > 0 {
+			recs = append(recs, fmt.Sprintf("Downloads from the %s stalled %d times, the connection is unstable", r.Name, r.Stalls))
+		}
+	}
+	if fastest == 0 {
+		return recs
+	}
+	if limit := int64(settings.SpeedLimit) * 1024; limit > 0 && limit < fastest/2 {
+		recs = append(recs, fmt.Sprintf("The app speed limit (%s/s) is well below the measured %s/s, consider raising or removing it", ByteCountIEC(limit), ByteCountIEC(fastest)))
+	}
+	if settings.has("threadLimit") && settings.ThreadLimit == 1 {
+		recs = append(recs, "The app thread limit is set to 1, so files download one at a time, raising it can speed up pack installs")
+	}
+	return recs
+}
+
+func printSpeedTestResult(r SpeedTestResult) {
+	if r.Error != "" && r.Bytes == 0 {
+		pterm.Error.Printfln("%s: %s", r.Name, r.Error)
+		return
+	}
+	line := fmt.Sprintf("%s: %s/s (%s in %.1fs after %dms latency, %d stalls)", r.Name, ByteCountIEC(r.BytesPerSecond), ByteCountIEC(r.Bytes), float64(r.Duration)/1000, r.Latency, r.Stalls)
+	if r.BytesPerSecond < slowDownloadSpeed || r.Stalls > 0 {
+		pterm.Warning.Println(line)
+		return
+	}
+	pterm.Success.Println(line)
+}
@@ -0,0 +1,30 @@
+package dbg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Time spent before the response headers counts as latency, not as slow
+// throughput
+func TestRunSpeedTest(t *testing.T) {
+	body := make([]byte, 1<<20)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	r := runSpeedTest(SpeedTestTarget{Name: "test", URL: server.URL}, 10*time.Second)
+	if r.Error != "" || r.Bytes != int64(len(body)) {
+		t.Fatalf("got %+v", r)
+	}
+	if r.Latency < 300 {
+		t.Errorf("latency %dms, want at least 300ms", r.Latency)
+	}
+	if r.Duration >= 300 {
+		t.Errorf("download took %dms, the wait for headers was included", r.Duration)
+	}
+}
@@ -1,10 +1,60 @@
 package dbg
 
 import (
+	"encoding/json"
+	"net/url"
 	"os/user"
+	"time"
 )
 
 type (
+	// Options are the command line options passed in from main
+	Options struct {
+		// ChecksSource is a file path or URL of network check definitions
+		ChecksSource string
+		// DNSResolvers are the public resolvers DNS answers are compared against
+		DNSResolvers []string
+		// SpeedTest enables the download throughput test
+		SpeedTest         bool
+		SpeedTestDuration time.Duration
+		SpeedTestURLs     []string
+		// NTPServer is queried for clock skew in addition to HTTP Date headers
+		NTPServer          string
+		ClockSkewThreshold time.Duration
+		// InstanceDirs are extra directories to search for instances
+		InstanceDirs []string
+		// PackManifestDir holds FTB pack version file lists for integrity checks
+		PackManifestDir string
+		// AppPorts are extra localhost ports to check for conflicts
+		AppPorts []int
+		// HTMLReport is the path to write a standalone HTML report to
+		HTMLReport string
+		// Summary prints a Markdown summary sized for a Discord message
+		Summary bool
+		// CopySummary copies the Markdown summary to the clipboard
+		CopySummary bool
+		// PasteURL is the base URL of the paste server, defaults to pste.me
+		PasteURL string
+		// Files smaller than InlineLimit bytes are embedded in the manifest,
+		// off by default as it changes log references, see schema.go
+		InlineLimit int64
+		// InlineEncoding is plain or base64, binary files always use base64
+		InlineEncoding string
+		// Files smaller than BatchLimit bytes share a multi-file paste, off by
+		// default like InlineLimit
+		BatchLimit int64
+		// MaxFileSize caps each uploaded file, larger files keep their head and tail
+		MaxFileSize int64
+		// UploadBudget caps the total bytes uploaded in one run
+		UploadBudget int64
+		// Compress gzips uploads when the paste server accepts it
+		Compress bool
+		// LogCount is how many recent logs to upload from each log folder
+		LogCount int
+		// LogDays skips logs not modified within this many days
+		LogDays int
+	}
+
 	FTBApp struct {
 		User *user.User
 		//OWLocation      string
@@ -13,32 +63,46 @@ type (
 		Settings        AppSettings
 	}
 
+	// AppSettings is the app's storage/settings.json, see settings.go for how
+	// values are read and written
 	AppSettings struct {
-		EnableAnalytics  string `json:"enableAnalytics"`
-		EnableBeta       string `json:"enableBeta"`
-		Memory           string `json:"memory"`
-		EnablePreview    string `json:"enablePreview"`
+		EnableAnalytics  bool   `json:"enableAnalytics"`
+		EnableBeta       bool   `json:"enableBeta"`
+		Memory           int    `json:"memory"`
+		EnablePreview    bool   `json:"enablePreview"`
 		SessionString    string `json:"sessionString"`
 		ListMode         string `json:"listMode"`
-		ShowAdverts      string `json:"showAdverts"`
-		AutoOpenChat     string `json:"autoOpenChat"`
-		PackCardSize     string `json:"packCardSize"`
-		ThreadLimit      string `json:"threadLimit"`
-		MtConnect        string `json:"mtConnect"`
-		Height           string `json:"height"`
+		ShowAdverts      bool   `json:"showAdverts"`
+		AutoOpenChat     bool   `json:"autoOpenChat"`
+		PackCardSize     int    `json:"packCardSize"`
+		ThreadLimit      int    `json:"threadLimit"`
+		MtConnect        bool   `json:"mtConnect"`
+		Height           int    `json:"height"`
 		Jvmargs          string `json:"jvmargs"`
-		CacheLife        string `json:"cacheLife"`
-		CloudSaves       string `json:"cloudSaves"`
+		CacheLife        int    `json:"cacheLife"`
+		CloudSaves       bool   `json:"cloudSaves"`
 		InstanceLocation string `json:"instanceLocation"`
-		SpeedLimit       string `json:"speedLimit"`
-		EnableChat       string `json:"enableChat"`
-		LoadInApp        string `json:"loadInApp"`
-		Verbose          string `json:"verbose"`
-		AutomateMojang   string `json:"automateMojang"`
-		KeepLauncherOpen string `json:"keepLauncherOpen"`
-		Width            string `json:"width"`
-		ExitOverwolf     string `json:"exitOverwolf"`
+		SpeedLimit       int    `json:"speedLimit"`
+		EnableChat       bool   `json:"enableChat"`
+		LoadInApp        bool   `json:"loadInApp"`
+		Verbose          bool   `json:"verbose"`
+		AutomateMojang   bool   `json:"automateMojang"`
+		KeepLauncherOpen bool   `json:"keepLauncherOpen"`
+		Width            int    `json:"width"`
+		ExitOverwolf     bool   `json:"exitOverwolf"`
 		BlockedUsers     string `json:"blockedUsers"`
+
+		// Extra holds keys this tool doesn't know about
+		Extra map[string]json.RawMessage `json:"-"`
+		// Problems are values that couldn't be converted to their type
+		Problems []string `json:"-"`
+		quoted   map[string]bool
+	}
+	SettingDiff struct {
+		Key     string `json:"key"`
+		Value   string `json:"value"`
+		Default string `json:"default,omitempty"`
+		Unknown bool   `json:"unknown,omitempty"`
 	}
 
 	Instance struct {
@@ -87,68 +151,289 @@ type (
 		//Profile bool
 	}
 
+	// Network check definitions, see checks.json
+	CheckDefinitions struct {
+		Checks []CheckURLStruct `json:"checks"`
+	}
 	CheckURLStruct struct {
-		Method             string
-		ValidateResponse   bool
-		ExpectedStatusCode int
-		ExpectedReponse    string
+		URL                 string            `json:"url"`
+		Method              string            `json:"method"`
+		Headers             map[string]string `json:"headers,omitempty"`
+		Body                string            `json:"body,omitempty"`
+		ExpectedStatusCodes []int             `json:"expectedStatus"`
+		ExpectedResponse    string            `json:"responseRegex,omitempty"`
+		Timeout             string            `json:"timeout,omitempty"`
+		Severity            string            `json:"severity,omitempty"`
+		OS                  []string          `json:"os,omitempty"`
+		Arch                []string          `json:"arch,omitempty"`
 	}
 
 	NetworkCheck struct {
-		URL     string
-		Success bool
-		Error   bool
-		Status  string
+		// Keys keep the Go field names written before the schema was
+		// versioned, the support bot reads them
+		URL         string              `json:"URL"`
+		Success     bool                `json:"Success"`
+		Error       bool                `json:"Error"`
+		Status      string              `json:"Status"`
+		Severity    string              `json:"Severity,omitempty"`
+		Certificate *CertificateSummary `json:"Certificate,omitempty"`
+
+		clockSkew time.Duration
+		hasDate   bool
+	}
+	CertificateSummary struct {
+		Subject       string   `json:"subject"`
+		Issuer        string   `json:"issuer"`
+		Root          string   `json:"root,omitempty"`
+		Chain         []string `json:"chain"`
+		NotBefore     int64    `json:"notBefore"`
+		NotAfter      int64    `json:"notAfter"`
+		Trusted       bool     `json:"trusted"`
+		UnknownRoot   bool     `json:"unknownRoot,omitempty"`
+		VerifyError   string   `json:"verifyError,omitempty"`
+		InterceptedBy string   `json:"interceptedBy,omitempty"`
+	}
+
+	DNSCheck struct {
+		Host     string               `json:"host"`
+		System   DNSAnswer            `json:"system"`
+		Public   map[string]DNSAnswer `json:"public,omitempty"`
+		Problems []string             `json:"problems,omitempty"`
+	}
+	DNSAnswer struct {
+		Addresses []string `json:"addresses,omitempty"`
+		NXDomain  bool     `json:"nxdomain"`
+		Error     string   `json:"error,omitempty"`
+	}
+
+	ProxyReport struct {
+		Environment    map[string]string `json:"environment,omitempty"`
+		System         []string          `json:"system,omitempty"`
+		JavaProperties []string          `json:"javaProperties,omitempty"`
+		Detected       []DetectedProxy   `json:"detected,omitempty"`
+	}
+	DetectedProxy struct {
+		URL          string            `json:"url"`
+		Sources      []string          `json:"sources"`
+		Total        int               `json:"total"`
+		DirectPassed int               `json:"directPassed"`
+		ProxyPassed  int               `json:"proxyPassed"`
+		Differences  []ProxyDifference `json:"differences,omitempty"`
+		// The URL with credentials and the hosts sent directly, never uploaded
+		proxy   *url.URL
+		noProxy []string
+	}
+	ProxyDifference struct {
+		URL        string `json:"url"`
+		DirectOk   bool   `json:"directOk"`
+		ViaProxyOk bool   `json:"viaProxyOk"`
+		Direct     string `json:"direct"`
+		ViaProxy   string `json:"viaProxy"`
+	}
+
+	IPFamilyCheck struct {
+		Host string       `json:"host"`
+		IPv4 FamilyResult `json:"ipv4"`
+		IPv6 FamilyResult `json:"ipv6"`
+	}
+	FamilyResult struct {
+		Ok        bool   `json:"ok"`
+		NoAddress bool   `json:"noAddress,omitempty"`
+		Status    string `json:"status,omitempty"`
+		Duration  int64  `json:"durationMs,omitempty"`
+	}
+
+	SpeedTestTarget struct {
+		Name string
+		URL  string
+	}
+	SpeedTestResult struct {
+		Name           string `json:"name"`
+		URL            string `json:"url"`
+		Size           int64  `json:"size,omitempty"`
+		Bytes          int64  `json:"bytes"`
+		Latency        int64  `json:"latencyMs"`
+		Duration       int64  `json:"durationMs"`
+		BytesPerSecond int64  `json:"bytesPerSecond"`
+		Stalls         int    `json:"stalls"`
+		Error          string `json:"error,omitempty"`
+	}
+
+	ProcessInfo struct {
+		PID         int32    `json:"pid"`
+		Kind        string   `json:"kind"`
+		Name        string   `json:"name"`
+		Cmdline     string   `json:"cmdline,omitempty"`
+		Memory      int64    `json:"memory"`
+		CPUPercent  float64  `json:"cpuPercent"`
+		Started     int64    `json:"started,omitempty"`
+		ListenPorts []uint32 `json:"listenPorts,omitempty"`
+		InstanceDir string   `json:"instanceDir,omitempty"`
+		Instance    string   `json:"instance,omitempty"`
+	}
+
+	PortReport struct {
+		LoopbackOk    bool        `json:"loopbackOk"`
+		LoopbackError string      `json:"loopbackError,omitempty"`
+		Ports         []PortCheck `json:"ports,omitempty"`
+	}
+	PortCheck struct {
+		Port      int    `json:"port"`
+		Source    string `json:"source"`
+		Bound     bool   `json:"bound"`
+		OwnerPID  int32  `json:"ownerPid,omitempty"`
+		Owner     string `json:"owner,omitempty"`
+		Conflict  bool   `json:"conflict"`
+		Reachable bool   `json:"reachable"`
+	}
+
+	SystemInfo struct {
+		OS          string `json:"os"`
+		Arch        string `json:"arch"`
+		CPU         string `json:"cpu,omitempty"`
+		MemoryTotal int64  `json:"memoryTotal,omitempty"`
+		MemoryUsed  int64  `json:"memoryUsed,omitempty"`
+		JavaHome    string `json:"javaHome,omitempty"`
 	}
 
 	// Manifest
+	// Manifest is the report uploaded for support. Its layout is versioned by
+	// SchemaVersion, see schema.go. Fields whose zero value means something,
+	// such as check outcomes and counts, are always present, optional details
+	// are omitted when empty.
 	Manifest struct {
-		Version                 string               `json:"version,omitempty"`
-		MetaDetails             MetaDetails          `json:"metaDetails,omitempty"`
-		AppDetails              AppDetails           `json:"appDetails,omitempty"`
+		SchemaVersion           int                  `json:"schemaVersion"`
+		Version                 string               `json:"version"`
+		MetaDetails             MetaDetails          `json:"metaDetails"`
+		System                  *SystemInfo          `json:"system,omitempty"`
+		AppDetails              AppDetails           `json:"appDetails"`
 		AppLogs                 map[string]string    `json:"appLogs,omitempty"`
 		ProviderInstanceMapping map[string]Instances `json:"providerInstanceMapping,omitempty"`
 		InstanceLogs            []InstanceLogs       `json:"instanceLogs,omitempty"`
 		NetworkChecks           []NetworkCheck       `json:"networkChecks,omitempty"`
+		DNSChecks               []DNSCheck           `json:"dnsChecks,omitempty"`
+		HostsFileEntries        []string             `json:"hostsFileEntries,omitempty"`
+		Proxy                   *ProxyReport         `json:"proxy,omitempty"`
+		IPFamilyChecks          []IPFamilyCheck      `json:"ipFamilyChecks,omitempty"`
+		SpeedTests              []SpeedTestResult    `json:"speedTests,omitempty"`
+		Login                   *LoginDiagnostics    `json:"login,omitempty"`
+		Processes               []ProcessInfo        `json:"processes,omitempty"`
+		Ports                   *PortReport          `json:"ports,omitempty"`
+		SettingsProblems        []string             `json:"settingsProblems,omitempty"`
+		SettingsDiff            []SettingDiff        `json:"settingsDiff,omitempty"`
+		Recommendations         []string             `json:"recommendations,omitempty"`
+		InlineFiles             []InlineFile         `json:"inlineFiles,omitempty"`
+		Batches                 []UploadBatch        `json:"batches,omitempty"`
+		Truncated               []TruncatedUpload    `json:"truncated,omitempty"`
+	}
+	// Files cut down or skipped to stay within the upload budgets
+	TruncatedUpload struct {
+		Name         string `json:"name"`
+		Ref          string `json:"ref,omitempty"`
+		OriginalSize int64  `json:"originalSize"`
+		UploadedSize int64  `json:"uploadedSize"`
+		Skipped      bool   `json:"skipped"`
+	}
+	// Files embedded in the manifest, referenced as inline:<index>
+	InlineFile struct {
+		Name     string `json:"name"`
+		Encoding string `json:"encoding"`
+		Content  string `json:"content"`
+	}
+	// Multi-file pastes, files are referenced as batch:<batch>:<file>
+	UploadBatch struct {
+		ID    string      `json:"id"`
+		Files []BatchFile `json:"files"`
+	}
+	BatchFile struct {
+		Name      string `json:"name"`
+		StartLine int    `json:"startLine"`
+		Lines     int    `json:"lines"`
 	}
 	MetaDetails struct {
-		InstanceCount     int    `json:"instanceCount,omitempty"`
-		Today             string `json:"today,omitempty"`
-		Time              int64  `json:"time,omitempty"`
-		AddedAccounts     int    `json:"addedAccounts,omitempty"`
+		InstanceCount     int    `json:"instanceCount"`
+		Today             string `json:"today"`
+		Time              int64  `json:"time"`
+		AddedAccounts     int    `json:"addedAccounts"`
 		HasActiveAccounts bool   `json:"hasActiveAccounts"`
+		ClockSkew         int64  `json:"clockSkewSeconds,omitempty"`
+		ClockSkewSource   string `json:"clockSkewSource,omitempty"`
 	}
 	AppDetails struct {
 		App           string  `json:"app,omitempty"`
 		SharedVersion string  `json:"sharedVersion,omitempty"`
-		Meta          AppMeta `json:"meta,omitempty"`
+		Meta          AppMeta `json:"meta"`
 	}
 	Instances struct {
-		Name                       string `json:"name,omitempty"`
-		PackType                   int    `json:"packType"`
-		PackId                     int    `json:"packId,omitempty"`
-		PackVersion                int    `json:"packVersion,omitempty"`
-		Version                    string `json:"version,omitempty"`
-		UUID                       string `json:"uuid,omitempty"`
-		McVersion                  string `json:"mcVersion,omitempty"`
-		MinMemory                  int    `json:"minMemory,omitempty"`
-		RecMemory                  int    `json:"recMemory,omitempty"`
-		Memory                     int    `json:"memory,omitempty"`
-		JvmArgs                    string `json:"jvmArgs,omitempty"`
-		ShellArgs                  string `json:"shellArgs,omitempty"`
-		EmbeddedJre                bool   `json:"embeddedJre,omitempty"`
-		JrePath                    string `json:"jrePath,omitempty"`
-		ModLoader                  string `json:"modLoader,omitempty"`
-		IsModified                 bool   `json:"isModified,omitempty"`
-		IsImport                   bool   `json:"isImport,omitempty"`
-		HasInstMods                bool   `json:"hasInstMods,omitempty"`
-		InstallComplete            bool   `json:"installComplete,omitempty"`
-		ReleaseChannel             string `json:"releaseChannel,omitempty"`
-		Locked                     bool   `json:"locked,omitempty"`
-		PreventMetaModInjection    bool   `json:"preventMetaModInjection,omitempty"`
-		Private                    bool   `json:"_private,omitempty"`
-		LastPlayed                 int    `json:"lastPlayed,omitempty"`
-		PotentiallyBrokenDismissed bool   `json:"potentiallyBrokenDismissed,omitempty"`
+		Name                       string           `json:"name,omitempty"`
+		PackType                   int              `json:"packType"`
+		PackId                     int              `json:"packId,omitempty"`
+		PackVersion                int              `json:"packVersion,omitempty"`
+		Version                    string           `json:"version,omitempty"`
+		UUID                       string           `json:"uuid,omitempty"`
+		McVersion                  string           `json:"mcVersion,omitempty"`
+		MinMemory                  int              `json:"minMemory,omitempty"`
+		RecMemory                  int              `json:"recMemory,omitempty"`
+		Memory                     int              `json:"memory,omitempty"`
+		JvmArgs                    string           `json:"jvmArgs,omitempty"`
+		ShellArgs                  string           `json:"shellArgs,omitempty"`
+		EmbeddedJre                bool             `json:"embeddedJre,omitempty"`
+		JrePath                    string           `json:"jrePath,omitempty"`
+		ModLoader                  string           `json:"modLoader,omitempty"`
+		IsModified                 bool             `json:"isModified,omitempty"`
+		IsImport                   bool             `json:"isImport,omitempty"`
+		HasInstMods                bool             `json:"hasInstMods,omitempty"`
+		InstallComplete            bool             `json:"installComplete,omitempty"`
+		ReleaseChannel             string           `json:"releaseChannel,omitempty"`
+		Locked                     bool             `json:"locked,omitempty"`
+		PreventMetaModInjection    bool             `json:"preventMetaModInjection,omitempty"`
+		Private                    bool             `json:"_private,omitempty"`
+		LastPlayed                 int              `json:"lastPlayed,omitempty"`
+		PotentiallyBrokenDismissed bool             `json:"potentiallyBrokenDismissed,omitempty"`
+		Location                   string           `json:"location,omitempty"`
+		Orphaned                   bool             `json:"orphaned,omitempty"`
+		Integrity                  *IntegrityReport `json:"integrity,omitempty"`
+		Size                       *InstanceSize    `json:"size,omitempty"`
+		Worlds                     []WorldInfo      `json:"worlds,omitempty"`
+	}
+	InstanceSize struct {
+		Total     int64            `json:"total"`
+		Breakdown map[string]int64 `json:"breakdown,omitempty"`
+	}
+	WorldInfo struct {
+		Folder      string `json:"folder"`
+		Name        string `json:"name,omitempty"`
+		Size        int64  `json:"size"`
+		Modified    int64  `json:"modified,omitempty"`
+		GameVersion string `json:"gameVersion,omitempty"`
+		DataVersion int    `json:"dataVersion,omitempty"`
+		LastPlayed  int64  `json:"lastPlayed,omitempty"`
+		Error       string `json:"error,omitempty"`
+	}
+	IntegrityReport struct {
+		Source   string   `json:"source"`
+		Checked  int      `json:"checked"`
+		Complete bool     `json:"complete"`
+		Missing  []string `json:"missing,omitempty"`
+		Modified []string `json:"modified,omitempty"`
+		Extra    []string `json:"extra,omitempty"`
+	}
+	// FTB pack version file list, as returned by the modpacks API
+	PackVersionManifest struct {
+		ID    int        `json:"id"`
+		Files []PackFile `json:"files"`
+	}
+	PackFile struct {
+		Path       string `json:"path"`
+		Name       string `json:"name"`
+		Sha1       string `json:"sha1"`
+		Size       int64  `json:"size"`
+		ClientOnly bool   `json:"clientonly"`
+		ServerOnly bool   `json:"serveronly"`
+	}
+	InstanceRoot struct {
+		Path   string
+		Source string
 	}
 	InstanceLogs struct {
 		Created   int64             `json:"created,omitempty"`
@@ -158,6 +443,17 @@ type (
 		ModLoader string            `json:"modLoader,omitempty"`
 		Logs      map[string]string `json:"logs,omitempty"`
 		CrashLogs map[string]string `json:"crashLogs,omitempty"`
+		// Log lines written around each crash, keyed by crash report name
+		CrashExcerpts map[string][]CrashExcerpt `json:"crashExcerpts,omitempty"`
+
+		// When each crash report was written, for the app log excerpts
+		crashTimes map[string]time.Time
+	}
+	CrashExcerpt struct {
+		Log  string `json:"log"`
+		Ref  string `json:"ref"`
+		From int64  `json:"from"`
+		To   int64  `json:"to"`
 	}
 
 	// Pste.me response
@@ -187,16 +483,36 @@ type (
 	}
 
 	Profiles struct {
-		Version  string `json:"version"`
-		Profiles []struct {
-			UUID                          string `json:"uuid"`
-			LastLogin                     int    `json:"lastLogin,omitempty"`
-			Username                      string `json:"username,omitempty"`
-			MinecraftUsername             string `json:"minecraftUsername,omitempty"`
-			MinecraftAccessTokenExpiresAt int    `json:"minecraftAccessExpiresAt,omitempty"`
-			MicrosoftAccessTokenExpiresAt int    `json:"microsoftAccessExpiresAt,omitempty"`
-			NotLoggedIn                   bool   `json:"notLoggedIn,omitempty"`
-		} `json:"profiles"`
-		ActiveProfile string `json:"activeProfile"`
+		Version       string    `json:"version"`
+		Profiles      []Profile `json:"profiles"`
+		ActiveProfile string    `json:"activeProfile"`
+	}
+	Profile struct {
+		UUID                          string `json:"uuid"`
+		LastLogin                     int    `json:"lastLogin,omitempty"`
+		Username                      string `json:"username,omitempty"`
+		MinecraftUsername             string `json:"minecraftUsername,omitempty"`
+		MinecraftAccessTokenExpiresAt int    `json:"minecraftAccessExpiresAt,omitempty"`
+		MicrosoftAccessTokenExpiresAt int    `json:"microsoftAccessExpiresAt,omitempty"`
+		NotLoggedIn                   bool   `json:"notLoggedIn,omitempty"`
+	}
+
+	LoginDiagnostics struct {
+		Accounts            []AccountDiagnostic `json:"accounts,omitempty"`
+		ActiveProfileExists bool                `json:"activeProfileExists"`
+		AuthReachable       bool                `json:"authReachable"`
+		FailedEndpoints     []string            `json:"failedEndpoints,omitempty"`
+		LoginProblem        bool                `json:"loginProblem"`
+		Verdict             string              `json:"verdict"`
+	}
+	AccountDiagnostic struct {
+		ID                      string `json:"id"`
+		Active                  bool   `json:"active"`
+		NotLoggedIn             bool   `json:"notLoggedIn,omitempty"`
+		MinecraftTokenExpired   bool   `json:"minecraftTokenExpired"`
+		MinecraftTokenExpiresAt int64  `json:"minecraftTokenExpiresAt,omitempty"`
+		MicrosoftTokenExpired   bool   `json:"microsoftTokenExpired"`
+		MicrosoftTokenExpiresAt int64  `json:"microsoftTokenExpiresAt,omitempty"`
+		DaysSinceLogin          int    `json:"daysSinceLogin"`
 	}
 )
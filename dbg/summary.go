@@ -0,0 +1,150 @@
+package dbg
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// Discord rejects messages longer than this without Nitro
+	discordMessageLimit = 2000
+	summaryLineLimit    = 160
+)
+
+type summaryList struct {
+	title string
+	lines []string
+}
+
+// markdownSummary renders the key findings of a manifest as Markdown that fits
+// in a single Discord message. Lists are shortened until the summary fits.
+func markdownSummary(m Manifest, code string) string {
+	var header []string
+	if code != "" {
+		header = append(header, fmt.Sprintf("**FTB Debug** `dbg:%s`", code))
+	} else {
+		header = append(header, "**FTB Debug** (manifest not uploaded)")
+	}
+	if m.System != nil {
+		header = append(header, fmt.Sprintf("**OS:** %s (%s)", m.System.OS, m.System.Arch))
+	}
+	if meta := m.AppDetails.Meta; meta.AppVersion != "" {
+		header = append(header, fmt.Sprintf("**App:** %s (%s)", meta.AppVersion, meta.Branch))
+	}
+
+	var failed []string
+	for _, n := range m.NetworkChecks {
+		if !n.Success {
+			failed = append(failed, summaryLine(fmt.Sprintf("- %s: ", n.URL), n.Status, ""))
+		}
+	}
+
+	var crashed []string
+	for _, l := range m.InstanceLogs {
+		if len(l.CrashLogs) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(l.CrashLogs))
+		for name := range l.CrashLogs {
+			names = append(names, name)
+		}
+		// Crash reports are named crash-<date>-<side>.txt so the last sorts newest
+		slices.Sort(names)
+		newest := names[len(names)-1]
+		ref := l.CrashLogs[newest]
+		link := m.publicLink(ref)
+		if link == "" && code != "" {
+			link = pasteLink(code)
+		}
+		var suffix string
+		if link != "" {
+			suffix += fmt.Sprintf(", newest <%s>", link)
+		}
+		if where := m.refLocation(ref); where != "" {
+			suffix += " " + where
+		}
+		crashed = append(crashed, summaryLine("- ", fmt.Sprintf("%s (%s %s): %d crash report(s)", l.Name, l.McVersion, l.ModLoader, len(names)), suffix))
+	}
+
+	var problems []string
+	for _, r := range m.Recommendations {
+		problems = append(problems, summaryLine("- ", r, ""))
+	}
+
+	lists := []summaryList{
+		{fmt.Sprintf("**Failed checks (%d)**", len(failed)), failed},
+		{fmt.Sprintf("**Crashed instances (%d)**", len(crashed)), crashed},
+		{fmt.Sprintf("**Problems (%d)**", len(problems)), problems},
+	}
+	if len(failed) == 0 && len(crashed) == 0 && len(problems) == 0 {
+		header = append(header, "No problems detected")
+	}
+
+	var summary string
+	for _, limit := range []int{10, 5, 3, 1, 0} {
+		summary = renderSummary(header, lists, limit)
+		if utf8.RuneCountInString(summary) <= discordMessageLimit {
+			return summary
+		}
+	}
+	return truncateRunes(summary, discordMessageLimit)
+}
+
+func renderSummary(header []string, lists []summaryList, limit int) string {
+	var sb strings.Builder
+	sb.WriteString(strings.Join(header, "\n"))
+	for _, l := range lists {
+		if len(l.lines) == 0 {
+			continue
+		}
+		sb.WriteString("\n" + l.title)
+		for i, line := range l.lines {
+			if i == limit {
+				sb.WriteString(fmt.Sprintf("\n- ...and %d more", len(l.lines)-limit))
+				break
+			}
+			sb.WriteString("\n" + line)
+		}
+	}
+	return sb.String()
+}
+
+// summaryLine builds a list item of at most summaryLineLimit runes. Only text
+// is shortened, prefix and suffix hold URLs that must stay whole. Whitespace
+// in text is collapsed as a newline would end the Markdown list item.
+func summaryLine(prefix, text, suffix string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	room := summaryLineLimit - utf8.RuneCountInString(prefix) - utf8.RuneCountInString(suffix)
+	return prefix + truncateRunes(text, max(room, 1)) + suffix
+}
+
+func truncateRunes(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:limit-1]) + "…"
+}
+
+// copyToClipboard pipes text into the first clipboard tool that works
+func copyToClipboard(text string) error {
+	var errs []error
+	for _, args := range clipboardCommands() {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", args[0], err))
+			continue
+		}
+		return nil
+	}
+	return errors.Join(errs...)
+}
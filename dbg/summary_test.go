@@ -0,0 +1,34 @@
+package dbg
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestMarkdownSummaryLines(t *testing.T) {
+	useFixture(t, nil, Options{})
+	link := pasteLink("crashpaste")
+	m := Manifest{
+		NetworkChecks: []NetworkCheck{{URL: "https://api.modpacks.ch", Status: "connection failed:\n  dial tcp\r\n\ttimeout"}},
+		InstanceLogs: []InstanceLogs{{
+			Name:      strings.Repeat("Very Long Pack Name ", 10),
+			McVersion: "1.20.1",
+			ModLoader: "forge",
+			CrashLogs: map[string]string{"crash-2024-01-01_10.00.00-client.txt": "crashpaste"},
+		}},
+	}
+	summary := markdownSummary(m, "code")
+
+	if !strings.Contains(summary, "\n- https://api.modpacks.ch: connection failed: dial tcp timeout\n") {
+		t.Errorf("status not collapsed onto one line:\n%s", summary)
+	}
+	if !strings.Contains(summary, ", newest <"+link+">") {
+		t.Errorf("crash link cut:\n%s", summary)
+	}
+	for _, line := range strings.Split(summary, "\n") {
+		if n := utf8.RuneCountInString(line); n > summaryLineLimit {
+			t.Errorf("line has %d runes: %s", n, line)
+		}
+	}
+}
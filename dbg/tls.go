@@ -0,0 +1,154 @@
+package dbg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+const tlsTimeout = 10 * time.Second
+
+// Issuer names used by antivirus, parental control and corporate proxy
+// products that intercept HTTPS traffic
+var interceptionProducts = []string{
+	"avast", "avg technologies", "avg web", "kaspersky", "eset ssl", "bitdefender", "norton", "mcafee",
+	"sophos", "fortinet", "fortigate", "zscaler", "palo alto", "netskope", "blue coat", "bluecoat",
+	"trend micro", "webroot", "forcepoint", "untangle", "fiddler", "charles proxy", "mitmproxy",
+	"cisco umbrella", "opendns", "barracuda", "sonicwall", "watchguard", "securly", "lightspeed",
+	"goguardian", "smoothwall", "iboss", "check point", "checkpoint", "avira", "dr.web", "f-secure",
+	"g data", "qustodio", "net nanny", "cyberoam", "contentkeeper", "adguard",
+}
+
+// inspectTLS connects to host and summarises the certificate chain it
+// presents. Verification is done separately from the handshake so the chain
+// can still be recorded when it doesn't validate.
+func inspectTLS(host string) (*CertificateSummary, error) {
+	dialer := &net.Dialer{Timeout: tlsTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, "443"), &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates presented")
+	}
+	leaf := certs[0]
+	summary := &CertificateSummary{
+		Subject:   leaf.Subject.CommonName,
+		Issuer:    certName(leaf.Issuer.CommonName, leaf.Issuer.Organization),
+		NotBefore: leaf.NotBefore.Unix(),
+		NotAfter:  leaf.NotAfter.Unix(),
+	}
+	for _, c := range certs {
+		summary.Chain = append(summary.Chain, certName(c.Subject.CommonName, c.Subject.Organization))
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	chains, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Intermediates: intermediates})
+	if err != nil {
+		summary.VerifyError = err.Error()
+		var unknownAuthority x509.UnknownAuthorityError
+		summary.UnknownRoot = errors.As(err, &unknownAuthority)
+	} else {
+		summary.Trusted = true
+		if len(chains) > 0 && len(chains[0]) > 0 {
+			root := chains[0][len(chains[0])-1]
+			summary.Root = certName(root.Subject.CommonName, root.Subject.Organization)
+			summary.Chain = summary.Chain[:0]
+			for _, c := range chains[0] {
+				summary.Chain = append(summary.Chain, certName(c.Subject.CommonName, c.Subject.Organization))
+			}
+		}
+	}
+
+	for _, name := range slices.Concat(summary.Chain, []string{summary.Issuer, summary.Root}) {
+		if product := interceptionProduct(name); product != "" {
+			summary.InterceptedBy = product
+			break
+		}
+	}
+	return summary, nil
+}
+
+func certName(commonName string, organization []string) string {
+	if len(organization) > 0 && organization[0] != commonName {
+		if commonName == "" {
+			return organization[0]
+		}
+		return fmt.Sprintf("%s (%s)", commonName, organization[0])
+	}
+	return commonName
+}
+
+func interceptionProduct(name string) string {
+	lower := strings.ToLower(name)
+	for _, product := range interceptionProducts {
+		if strings.Contains(lower, product) {
+			return name
+		}
+	}
+	return ""
+}
+
+func tlsHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// problems describes anything about the certificate support should know about
+func (c *CertificateSummary) problems() []string {
+	var problems []string
+	if c.InterceptedBy != "" {
+		problems = append(problems, fmt.Sprintf("HTTPS is being intercepted by %s, this is usually antivirus or a proxy and can break Java", c.InterceptedBy))
+	} else if c.UnknownRoot {
+		problems = append(problems, fmt.Sprintf("certificate issued by %s does not chain to a trusted root, HTTPS is likely being intercepted", c.Issuer))
+	} else if c.VerifyError != "" {
+		problems = append(problems, fmt.Sprintf("certificate failed to verify: %s", c.VerifyError))
+	}
+	now := time.Now()
+	if now.After(time.Unix(c.NotAfter, 0)) {
+		problems = append(problems, fmt.Sprintf("certificate expired on %s, check the system clock", time.Unix(c.NotAfter, 0).UTC().Format(time.DateOnly)))
+	} else if now.Before(time.Unix(c.NotBefore, 0)) {
+		problems = append(problems, fmt.Sprintf("certificate is not valid until %s, check the system clock", time.Unix(c.NotBefore, 0).UTC().Format(time.DateOnly)))
+	}
+	return problems
+}
+
+func printTLSChecks(nc []NetworkCheck) {
+	seen := make(map[string]bool)
+	for _, n := range nc {
+		host := tlsHost(n.URL)
+		if host == "" || seen[host] || n.Certificate == nil {
+			continue
+		}
+		seen[host] = true
+		problems := n.Certificate.problems()
+		if len(problems) == 0 {
+			pterm.Success.Printfln("%s: issued by %s", host, n.Certificate.Issuer)
+			continue
+		}
+		for _, problem := range problems {
+			pterm.Error.Printfln("%s: %s", host, problem)
+		}
+		pterm.Debug.Printfln("%s: chain %s", host, strings.Join(n.Certificate.Chain, " -> "))
+	}
+}
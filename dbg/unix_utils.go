@@ -8,6 +8,7 @@ import (
 	"os/exec"
 	"regexp"
 	"runtime"
+	"strings"
 )
 
 func getSysInfo() (oSystem string, err error) {
@@ -41,3 +42,81 @@ func getSysInfo() (oSystem string, err error) {
 		return "", errors.New("unable to determine operating system")
 	}
 }
+
+// getSystemProxy returns the desktop proxy settings keyed by protocol. The
+// bypass lists of macOS and GNOME aren't read, so no hosts are returned.
+func getSystemProxy() (map[string]string, []string, error) {
+	proxies := make(map[string]string)
+	switch runtime.GOOS {
+	case "darwin":
+		out, err := exec.Command("scutil", "--proxy").Output()
+		if err != nil {
+			return nil, nil, err
+		}
+		values := make(map[string]string)
+		for _, line := range strings.Split(string(out), "\n") {
+			key, value, ok := strings.Cut(line, " : ")
+			if ok {
+				values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		}
+		for _, proto := range []string{"HTTP", "HTTPS", "SOCKS"} {
+			if values[proto+"Enable"] == "1" && values[proto+"Proxy"] != "" {
+				scheme := "http"
+				if proto == "SOCKS" {
+					scheme = "socks5"
+				}
+				proxies[strings.ToLower(proto)] = fmt.Sprintf("%s://%s:%s", scheme, values[proto+"Proxy"], values[proto+"Port"])
+			}
+		}
+		if values["ProxyAutoConfigEnable"] == "1" {
+			proxies["pac"] = values["ProxyAutoConfigURLString"]
+		}
+		return proxies, nil, nil
+	case "linux":
+		out, err := exec.Command("gsettings", "get", "org.gnome.system.proxy", "mode").Output()
+		if err != nil {
+			return nil, nil, err
+		}
+		mode := strings.Trim(strings.TrimSpace(string(out)), "'")
+		if mode == "auto" {
+			pac, _ := exec.Command("gsettings", "get", "org.gnome.system.proxy", "autoconfig-url").Output()
+			proxies["pac"] = strings.Trim(strings.TrimSpace(string(pac)), "'")
+		}
+		if mode != "manual" {
+			return proxies, nil, nil
+		}
+		for _, proto := range []string{"http", "https", "socks"} {
+			host, err := exec.Command("gsettings", "get", "org.gnome.system.proxy."+proto, "host").Output()
+			if err != nil {
+				continue
+			}
+			port, _ := exec.Command("gsettings", "get", "org.gnome.system.proxy."+proto, "port").Output()
+			h := strings.Trim(strings.TrimSpace(string(host)), "'")
+			if h == "" {
+				continue
+			}
+			scheme := "http"
+			if proto == "socks" {
+				scheme = "socks5"
+			}
+			proxies[proto] = fmt.Sprintf("%s://%s:%s", scheme, h, strings.TrimSpace(string(port)))
+		}
+		return proxies, nil, nil
+	default:
+		return nil, nil, errors.New("unable to determine operating system")
+	}
+}
+
+// clipboardCommands lists the clipboard tools to try in order, each reading
+// from stdin
+func clipboardCommands() [][]string {
+	if runtime.GOOS == "darwin" {
+		return [][]string{{"pbcopy"}}
+	}
+	return [][]string{
+		{"wl-copy"},
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+	}
+}
@@ -0,0 +1,383 @@
+package dbg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pterm/pterm"
+)
+
+const (
+	inlineRefPrefix = "inline:"
+	batchRefPrefix  = "batch:"
+	// Largest combined size of the files in one batch paste
+	batchPasteSize = 1 << 20
+	// Files are skipped rather than cut down to less than this
+	minTruncatedSize = 16 << 10
+	// One in this many bytes of the budget is kept for important files
+	importantReserve = 4
+)
+
+// uploadQueue decides how each collected file reaches support. Small files
+// are inlined into the manifest, medium files are grouped into shared batch
+// pastes and anything larger is uploaded on its own. The reference returned
+// by add replaces the paste id in the manifest's log maps.
+type uploadQueue struct {
+	inline    []InlineFile
+	batches   []pendingBatch
+	truncated []TruncatedUpload
+	spent     int64
+}
+
+type pendingBatch struct {
+	files []BatchFile
+	data  [][]byte
+	size  int
+}
+
+var uploads uploadQueue
+
+var errEmptyUpload = errors.New("file is empty")
+
+// add queues a file for upload, cut down to fit the budget
+func (q *uploadQueue) add(name string, data []byte, lang string) (string, error) {
+	return q.queue(name, bytes.NewReader(data), lang, false)
+}
+
+// addImportant is add for the files support looks at first, latest.log, crash
+// reports and their excerpts. They may use the share of the budget the other
+// files have to leave free.
+func (q *uploadQueue) addImportant(name string, data []byte, lang string) (string, error) {
+	return q.queue(name, bytes.NewReader(data), lang, true)
+}
+
+// addReader is add for content that may be too large to hold in memory, such
+// as a decompressed log. Only as much as the budget allows is kept.
+func (q *uploadQueue) addReader(name string, r io.Reader, lang string) (string, error) {
+	return q.queue(name, r, lang, false)
+}
+
+func (q *uploadQueue) queue(name string, r io.Reader, lang string, important bool) (string, error) {
+	limit := q.limit(important)
+	data, size, err := readHeadTail(r, limit)
+	if err != nil {
+		pterm.Warning.Printfln("%s is truncated or corrupt, uploading the %s that could be read: %s", name, ByteCountIEC(size), err)
+	}
+	if size > int64(len(data)) {
+		record := TruncatedUpload{Name: name, OriginalSize: size}
+		if limit < minTruncatedSize {
+			record.Skipped = true
+			q.truncated = append(q.truncated, record)
+			return "", fmt.Errorf("upload budget used up, skipped %s (%s)", name, ByteCountIEC(size))
+		}
+		pterm.Warning.Printfln("%s is %s, uploading the first and last %s", name, ByteCountIEC(size), ByteCountIEC(limit/2))
+		record.UploadedSize = int64(len(data))
+		q.spent += record.UploadedSize
+		ref, err := q.place(name, data, lang)
+		if err == nil {
+			record.Ref = ref
+			q.truncated = append(q.truncated, record)
+		}
+		return ref, err
+	}
+	if len(data) == 0 {
+		return "", errEmptyUpload
+	}
+	q.spent += int64(len(data))
+	return q.place(name, data, lang)
+}
+
+// limit is the most a file may upload, or -1 without any limit. Unimportant
+// files leave a quarter of the total budget free.
+func (q *uploadQueue) limit(important bool) int64 {
+	limit := int64(-1)
+	if options.MaxFileSize > 0 {
+		limit = options.MaxFileSize
+	}
+	if options.UploadBudget > 0 {
+		left := options.UploadBudget - q.spent
+		if !important {
+			left -= options.UploadBudget / importantReserve
+		}
+		left = max(left, 0)
+		if limit < 0 || left < limit {
+			limit = left
+		}
+	}
+	return limit
+}
+
+// readHeadTail reads r whole when it fits in limit bytes. Anything larger
+// keeps about limit bytes from its start and end, cut at line breaks, with a
+// marker where the middle was removed. Only about limit bytes are held in
+// memory however much r holds. size is the number of bytes read from r.
+func readHeadTail(r io.Reader, limit int64) (data []byte, size int64, err error) {
+	if limit < 0 {
+		data, err = io.ReadAll(r)
+		return data, int64(len(data)), err
+	}
+	data, err = io.ReadAll(io.LimitReader(r, limit+1))
+	size = int64(len(data))
+	if size <= limit || err != nil {
+		return data, size, err
+	}
+
+	half := int(limit / 2)
+	head := data[:half]
+	if i := bytes.LastIndexByte(head, '\n'); i > 0 {
+		head = head[:i+1]
+	}
+	// The tail grows to twice its size before the start is dropped so the
+	// buffer is only copied once per half limit read
+	tail := append([]byte(nil), data[len(head):]...)
+	var removedLines int
+	dropTo := func(keep int) {
+		if drop := len(tail) - keep; drop > 0 {
+			removedLines += bytes.Count(tail[:drop], []byte("\n"))
+			tail = append(tail[:0], tail[drop:]...)
+		}
+	}
+	chunk := make([]byte, 32<<10)
+	for {
+		n, readErr := r.Read(chunk)
+		size += int64(n)
+		tail = append(tail, chunk[:n]...)
+		if len(tail) > 2*half {
+			dropTo(half)
+		}
+		if readErr != nil {
+			if readErr != io.EOF {
+				err = readErr
+			}
+			break
+		}
+	}
+	dropTo(half)
+	if i := bytes.IndexByte(tail, '\n'); i >= 0 && i < len(tail)-1 {
+		dropTo(len(tail) - i - 1)
+	}
+
+	removed := size - int64(len(head)) - int64(len(tail))
+	marker := fmt.Sprintf("\n===== truncated %s (%d lines) by the debug tool =====\n\n", ByteCountIEC(removed), removedLines)
+	out := make([]byte, 0, len(head)+len(marker)+len(tail))
+	out = append(out, head...)
+	out = append(out, marker...)
+	return append(out, tail...), size, err
+}
+
+func (q *uploadQueue) place(name string, data []byte, lang string) (string, error) {
+	size := int64(len(data))
+	switch {
+	case size < options.InlineLimit:
+		q.inline = append(q.inline, inlineFile(name, sanitize(data)))
+		return inlineRefPrefix + strconv.Itoa(len(q.inline)-1), nil
+	case size < options.BatchLimit:
+		if len(q.batches) == 0 || q.batches[len(q.batches)-1].size+len(data) > batchPasteSize {
+			q.batches = append(q.batches, pendingBatch{})
+		}
+		b := &q.batches[len(q.batches)-1]
+		b.files = append(b.files, BatchFile{Name: name})
+		b.data = append(b.data, data)
+		b.size += len(data)
+		return fmt.Sprintf("%s%d:%d", batchRefPrefix, len(q.batches)-1, len(b.files)-1), nil
+	default:
+		resp, err := uploadRequest(data, lang)
+		if err != nil {
+			return "", err
+		}
+		return resp.Data.ID, nil
+	}
+}
+
+func inlineFile(name string, data []byte) InlineFile {
+	if options.InlineEncoding == "base64" || !utf8.Valid(data) {
+		return InlineFile{Name: name, Encoding: "base64", Content: base64.StdEncoding.EncodeToString(data)}
+	}
+	return InlineFile{Name: name, Encoding: "plain", Content: string(data)}
+}
+
+// flush uploads the pending batches and records the inline files and batch
+// indexes in the manifest
+func (q *uploadQueue) flush(manifest *Manifest) {
+	manifest.InlineFiles = q.inline
+	manifest.Truncated = q.truncated
+	for _, b := range q.batches {
+		content := batchContent(&b)
+		batch := UploadBatch{Files: b.files}
+		resp, err := uploadRequest(content, "log")
+		if err != nil {
+			pterm.Error.Printfln("Failed to upload batch of %d files: %s", len(b.files), err)
+		} else {
+			batch.ID = resp.Data.ID
+		}
+		manifest.Batches = append(manifest.Batches, batch)
+	}
+	if len(q.inline) > 0 || len(q.batches) > 0 {
+		pterm.Debug.Printfln("Inlined %d files, batched files into %d pastes", len(q.inline), len(q.batches))
+	}
+}
+
+// batchContent joins the files of a batch behind an index and fills in the
+// line each file starts at
+func batchContent(b *pendingBatch) []byte {
+	headerLines := len(b.files) + 2
+	line := headerLines + 1
+	for i, data := range b.data {
+		line++ // file separator
+		b.files[i].StartLine = line
+		b.files[i].Lines = lineCount(data)
+		line += b.files[i].Lines
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("Files in this paste:\n")
+	for i, f := range b.files {
+		fmt.Fprintf(&buf, "  %d. %s (lines %d-%d)\n", i+1, f.Name, f.StartLine, f.StartLine+f.Lines-1)
+	}
+	buf.WriteString("\n")
+	for i, data := range b.data {
+		fmt.Fprintf(&buf, "===== %s =====\n", b.files[i].Name)
+		buf.Write(data)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
+
+func lineCount(data []byte) int {
+	n := bytes.Count(data, []byte("\n"))
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		n++
+	}
+	return n
+}
+
+// parseLogRef splits a batch:<batch>:<file> or inline:<file> reference
+func parseLogRef(ref string) (kind string, batch, file int, err error) {
+	switch {
+	case strings.HasPrefix(ref, inlineRefPrefix):
+		file, err = strconv.Atoi(strings.TrimPrefix(ref, inlineRefPrefix))
+		if err == nil && file < 0 {
+			err = errors.New("negative index")
+		}
+		return "inline", 0, file, err
+	case strings.HasPrefix(ref, batchRefPrefix):
+		b, f, _ := strings.Cut(strings.TrimPrefix(ref, batchRefPrefix), ":")
+		if batch, err = strconv.Atoi(b); err != nil {
+			return "", 0, 0, err
+		}
+		file, err = strconv.Atoi(f)
+		if err == nil && (batch < 0 || file < 0) {
+			err = errors.New("negative index")
+		}
+		return "batch", batch, file, err
+	default:
+		return "paste", 0, 0, nil
+	}
+}
+
+// logLink returns a URL for a log reference. Inline files become data URLs so
+// the HTML report stays self-contained.
+func (m Manifest) logLink(ref string) template.URL {
+	kind, batch, file, err := parseLogRef(ref)
+	switch {
+	case err != nil:
+		return ""
+	case kind == "inline":
+		if file >= len(m.InlineFiles) {
+			return ""
+		}
+		content, err := m.InlineFiles[file].decode()
+		if err != nil {
+			return ""
+		}
+		return template.URL("data:text/plain;charset=utf-8;base64," + base64.StdEncoding.EncodeToString(content))
+	case kind == "batch":
+		if batch >= len(m.Batches) || m.Batches[batch].ID == "" {
+			return ""
+		}
+		return template.URL(pasteLink(m.Batches[batch].ID))
+	default:
+		return template.URL(pasteLink(ref))
+	}
+}
+
+// publicLink is logLink for sharing outside the report. Inline files have no
+// link of their own so an empty string is returned for them, batched files
+// link to their batch and refLocation says where in it they are.
+func (m Manifest) publicLink(ref string) string {
+	if strings.HasPrefix(ref, inlineRefPrefix) {
+		return ""
+	}
+	return string(m.logLink(ref))
+}
+
+// refLocation describes where the file behind ref is when its link does not
+// lead straight to it, such as "lines 12-40 of a shared paste"
+func (m Manifest) refLocation(ref string) string {
+	kind, batch, file, err := parseLogRef(ref)
+	switch {
+	case err != nil:
+		return ""
+	case kind == "inline":
+		return "embedded in the manifest"
+	case kind == "batch":
+		if batch >= len(m.Batches) || file >= len(m.Batches[batch].Files) {
+			return ""
+		}
+		f := m.Batches[batch].Files[file]
+		return fmt.Sprintf("lines %d-%d of a shared paste", f.StartLine, f.StartLine+f.Lines-1)
+	default:
+		return ""
+	}
+}
+
+// logContent returns the content behind a log reference, fetching pastes
+// when needed
+func (m Manifest) logContent(ref string) ([]byte, error) {
+	kind, batch, file, err := parseLogRef(ref)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log reference %q: %w", ref, err)
+	}
+	switch kind {
+	case "inline":
+		if file >= len(m.InlineFiles) {
+			return nil, fmt.Errorf("inline file %d not in manifest", file)
+		}
+		return m.InlineFiles[file].decode()
+	case "batch":
+		if batch >= len(m.Batches) || file >= len(m.Batches[batch].Files) {
+			return nil, fmt.Errorf("batch file %s not in manifest", ref)
+		}
+		b := m.Batches[batch]
+		if b.ID == "" {
+			return nil, errors.New("batch was not uploaded")
+		}
+		data, err := fetchPaste(b.ID)
+		if err != nil {
+			return nil, err
+		}
+		f := b.Files[file]
+		lines := strings.SplitAfter(string(data), "\n")
+		start := min(max(f.StartLine-1, 0), len(lines))
+		end := min(start+f.Lines, len(lines))
+		return []byte(strings.Join(lines[start:end], "")), nil
+	default:
+		return fetchPaste(ref)
+	}
+}
+
+func (f InlineFile) decode() ([]byte, error) {
+	if f.Encoding == "base64" {
+		return base64.StdEncoding.DecodeString(f.Content)
+	}
+	return []byte(f.Content), nil
+}
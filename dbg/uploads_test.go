@@ -0,0 +1,136 @@
+package dbg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// countingReader generates numbered lines and records how much was read
+type countingReader struct {
+	lines, next int
+	pending     []byte
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	for len(c.pending) < len(p) && c.next < c.lines {
+		c.pending = fmt.Appendf(c.pending, "line %07d\n", c.next)
+		c.next++
+	}
+	if len(c.pending) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func TestReadHeadTail(t *testing.T) {
+	const lineLen = len("line 0000000\n")
+	tests := []struct {
+		name      string
+		lines     int
+		limit     int64
+		truncated bool
+	}{
+		{"fits", 100, 100 * int64(lineLen), false},
+		{"no limit", 100, -1, false},
+		{"just over", 101, 100 * int64(lineLen), true},
+		{"far over", 200000, 64 << 10, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, size, err := readHeadTail(&countingReader{lines: tt.lines}, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := int64(tt.lines * lineLen); size != want {
+				t.Errorf("size %d, want %d", size, want)
+			}
+			if !tt.truncated {
+				if int64(len(data)) != size {
+					t.Errorf("got %d bytes, want all %d", len(data), size)
+				}
+				return
+			}
+			head, tail, ok := strings.Cut(string(data), "\n===== truncated ")
+			if !ok {
+				t.Fatal("no truncation marker")
+			}
+			if !strings.HasPrefix(head, "line 0000000\n") {
+				t.Errorf("head starts with %q", head[:min(len(head), 20)])
+			}
+			if last := fmt.Sprintf("line %07d\n", tt.lines-1); !strings.HasSuffix(tail, last) {
+				t.Errorf("tail does not end with %q", last)
+			}
+			if int64(len(head)) > tt.limit/2 || int64(len(data)) > tt.limit+128 {
+				t.Errorf("kept %d bytes for a %d byte limit", len(data), tt.limit)
+			}
+			kept := strings.Count(head, "\n") + 1 + strings.Count(tail, "\n") - 3
+			var removed int
+			if _, err := fmt.Sscanf(tail[strings.Index(tail, "(")+1:], "%d lines", &removed); err != nil {
+				t.Fatal(err)
+			}
+			if kept+removed != tt.lines {
+				t.Errorf("%d lines kept and %d removed, want %d in total", kept, removed, tt.lines)
+			}
+		})
+	}
+}
+
+func TestUploadBudget(t *testing.T) {
+	useFixture(t, fstest.MapFS{}, Options{MaxFileSize: 64 << 10, UploadBudget: 128 << 10})
+	big := bytes.Repeat([]byte("0123456789abcde\n"), 8<<10) // 128 KiB
+
+	// Ordinary files leave a quarter of the budget for important ones
+	if _, err := uploads.add("app-1.log", big, "log"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uploads.add("app-2.log", big, "log"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uploads.add("app-3.log", big, "log"); err == nil {
+		t.Error("ordinary file used the reserved budget")
+	}
+	if _, err := uploads.addImportant("crash-2024-01-01_12.00.00-client.txt", big, "log"); err != nil {
+		t.Errorf("crash report skipped: %s", err)
+	}
+	if uploads.spent > 128<<10+1024 {
+		t.Errorf("spent %d bytes of a %d byte budget", uploads.spent, 128<<10)
+	}
+	var skipped []string
+	for _, r := range uploads.truncated {
+		if r.Skipped {
+			skipped = append(skipped, r.Name)
+		}
+	}
+	if len(skipped) != 1 || skipped[0] != "app-3.log" {
+		t.Errorf("skipped %v, want [app-3.log]", skipped)
+	}
+}
+
+// A corrupt gzip still uploads the part that could be decompressed
+func TestAddReaderCorruptGzip(t *testing.T) {
+	useFixture(t, fstest.MapFS{}, Options{})
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, _ = io.Copy(gz, &countingReader{lines: 10000})
+	_ = gz.Close()
+	corrupt := buf.Bytes()[:buf.Len()/2]
+
+	r, err := gzip.NewReader(bytes.NewReader(corrupt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := uploads.addReader("broken.log.gz", r, "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := uploaded(t, ref); !strings.HasPrefix(got, "line 0000000\n") {
+		t.Errorf("readable part not uploaded: %q", got[:min(len(got), 20)])
+	}
+}
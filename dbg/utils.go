@@ -2,10 +2,10 @@ package dbg
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"ftb-debug/v2/shared"
 	"github.com/google/uuid"
 	"github.com/pterm/pterm"
 	"github.com/shirou/gopsutil/v3/cpu"
@@ -17,6 +17,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -47,16 +48,14 @@ func ByteCountIEC(b int64) string {
 }
 
 func validateJson(message string, filePath string) (bool, error) {
-	jsonF := shared.DoesPathExist(filePath)
+	jsonF := pathExists(filePath)
 	if jsonF {
-		jsonFile, err := os.Open(filePath)
+		byteValue, err := platform.FS.ReadFile(filePath)
 		if err != nil {
 			pterm.Error.Println(message, ": failed to load file\n", err)
 			return false, err
 		}
 
-		defer jsonFile.Close()
-		byteValue, _ := io.ReadAll(jsonFile)
 		valid := json.Valid(byteValue)
 		if !valid {
 			pterm.Error.Println(fmt.Sprintf("%s: is invalid", message))
@@ -68,42 +67,67 @@ func validateJson(message string, filePath string) (bool, error) {
 	return false, errors.New(fmt.Sprintf("Unable to validate %s\nUnable to find file %s", message, filePath))
 }
 
-func getOSInfo() {
+func getOSInfo() SystemInfo {
 	cpuInfo, _ := cpu.Info()
 	memInfo, _ := mem.VirtualMemory()
+	info := SystemInfo{OS: runtime.GOOS, Arch: runtime.GOARCH}
 	oSystem, err := getSysInfo()
-	if err == nil {
-		if oSystem != "" {
-			pterm.Info.Println(fmt.Sprintf("OS: %s", oSystem))
-		} else {
-			pterm.Info.Println(fmt.Sprintf("OS: %s", runtime.GOOS))
-		}
-	} else {
-		pterm.Info.Println(fmt.Sprintf("OS: %s", runtime.GOOS))
+	if err == nil && oSystem != "" {
+		info.OS = oSystem
 	}
+	pterm.Info.Println(fmt.Sprintf("OS: %s", info.OS))
 
 	if len(cpuInfo) > 0 {
-		pterm.Info.Println(fmt.Sprintf("CPU: %s (%s)", cpuInfo[0].ModelName, cpuInfo[0].VendorID))
+		info.CPU = fmt.Sprintf("%s (%s)", cpuInfo[0].ModelName, cpuInfo[0].VendorID)
+		pterm.Info.Println(fmt.Sprintf("CPU: %s", info.CPU))
 	} else {
 		pterm.Info.Println("CPU: Unable to calculate")
 	}
 
-	pterm.Info.Println(fmt.Sprintf("Memory: %s / %s (%.2f%% used)", ByteCountIEC(int64(memInfo.Used)), ByteCountIEC(int64(memInfo.Total)), memInfo.UsedPercent))
+	if memInfo != nil {
+		info.MemoryTotal = int64(memInfo.Total)
+		info.MemoryUsed = int64(memInfo.Used)
+		pterm.Info.Println(fmt.Sprintf("Memory: %s / %s (%.2f%% used)", ByteCountIEC(int64(memInfo.Used)), ByteCountIEC(int64(memInfo.Total)), memInfo.UsedPercent))
+	}
 
 	javaHome := os.Getenv("JAVA_HOME")
 	if javaHome != "" {
+		info.JavaHome = string(sanitize([]byte(javaHome)))
 		pterm.Info.Println("Java Home:", javaHome)
 	}
+	return info
 }
 
 func uploadRequest(data []byte, lang string) (PsteMeResp, error) {
-	// http put request to https://pste.me/v1/paste
+	// http put request to <paste server>/v1/paste
 
 	sanitizedData := sanitize(data)
+	if options.Compress && !compressionUnsupported {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		_, _ = gz.Write(sanitizedData)
+		if err := gz.Close(); err == nil {
+			r, status, err := putPaste(buf.Bytes(), lang, true)
+			if status != http.StatusBadRequest && status != http.StatusUnsupportedMediaType {
+				return r, err
+			}
+			// The server doesn't accept compressed bodies, stop trying for this run
+			pterm.Debug.Println("Paste server rejected a compressed upload, sending uncompressed")
+			compressionUnsupported = true
+		}
+	}
+	r, _, err := putPaste(sanitizedData, lang, false)
+	return r, err
+}
+
+func putPaste(body []byte, lang string, gzipped bool) (PsteMeResp, int, error) {
 	client := &http.Client{}
-	req, err := http.NewRequest("PUT", "https://pste.me/v1/paste", bytes.NewBuffer(sanitizedData))
+	req, err := http.NewRequest("PUT", pasteBase()+"/v1/paste", bytes.NewBuffer(body))
 	if err != nil {
-		return PsteMeResp{}, err
+		return PsteMeResp{}, 0, err
+	}
+	if gzipped {
+		req.Header.Set("Content-Encoding", "gzip")
 	}
 	query := req.URL.Query()
 	query.Add("expires_at", strconv.FormatInt(time.Now().Add(time.Hour*2190).Unix()-time.Now().Unix(), 10))
@@ -113,21 +137,21 @@ func uploadRequest(data []byte, lang string) (PsteMeResp, error) {
 	req.URL.RawQuery = query.Encode()
 	resp, err := client.Do(req)
 	if err != nil {
-		return PsteMeResp{}, err
+		return PsteMeResp{}, 0, err
 	}
 	defer resp.Body.Close()
 	content, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return PsteMeResp{}, err
+		return PsteMeResp{}, resp.StatusCode, err
 	}
 	if resp.StatusCode != 200 {
-		return PsteMeResp{}, fmt.Errorf("invalid status code: %d\n%s", resp.StatusCode, string(content))
+		return PsteMeResp{}, resp.StatusCode, fmt.Errorf("invalid status code: %d\n%s", resp.StatusCode, string(content))
 	}
 	var r PsteMeResp
 	if err := json.Unmarshal(content, &r); err != nil {
-		return PsteMeResp{}, err
+		return PsteMeResp{}, resp.StatusCode, err
 	}
-	return r, nil
+	return r, resp.StatusCode, nil
 }
 
 func sanitize(data []byte) []byte {
@@ -144,30 +168,30 @@ func sanitize(data []byte) []byte {
 }
 
 func doesBinExist() {
-	binExists := shared.DoesPathExist(filepath.Join(ftbApp.InstallLocation, "bin"))
+	binExists := pathExists(filepath.Join(ftbApp.InstallLocation, "bin"))
 	if binExists {
 		ftbApp.Structure.Bin.Exists = true
 	}
 }
 
 func locateFTBAFolder() (string, error) {
-	if runtime.GOOS == "windows" {
-		if shared.DoesPathExist(filepath.Join(os.Getenv("localappdata"), ".ftba")) {
-			return filepath.Join(os.Getenv("localappdata"), ".ftba"), nil
-		} else if shared.DoesPathExist(filepath.Join(ftbApp.User.HomeDir, ".ftba")) {
-			return filepath.Join(ftbApp.User.HomeDir, ".ftba"), nil
+	if platform.GOOS == "windows" {
+		if pathExists(filepath.Join(platform.LocalAppData, ".ftba")) {
+			return filepath.Join(platform.LocalAppData, ".ftba"), nil
+		} else if pathExists(filepath.Join(platform.HomeDir, ".ftba")) {
+			return filepath.Join(platform.HomeDir, ".ftba"), nil
 		} else {
 			return "", errors.New("unable to find .ftba directory")
 		}
-	} else if runtime.GOOS == "darwin" {
-		if shared.DoesPathExist(filepath.Join(os.Getenv("HOME"), "Library", "Application Support", ".ftba")) {
-			return filepath.Join(os.Getenv("HOME"), "Library", "Application Support", ".ftba"), nil
+	} else if platform.GOOS == "darwin" {
+		if pathExists(filepath.Join(platform.HomeDir, "Library", "Application Support", ".ftba")) {
+			return filepath.Join(platform.HomeDir, "Library", "Application Support", ".ftba"), nil
 		} else {
 			return "", errors.New("unable to find .ftba directory")
 		}
-	} else if runtime.GOOS == "linux" {
-		if shared.DoesPathExist(filepath.Join(ftbApp.User.HomeDir, ".ftba")) {
-			return filepath.Join(ftbApp.User.HomeDir, ".ftba"), nil
+	} else if platform.GOOS == "linux" {
+		if pathExists(filepath.Join(platform.HomeDir, ".ftba")) {
+			return filepath.Join(platform.HomeDir, ".ftba"), nil
 		} else {
 			return "", errors.New("unable to find .ftba directory")
 		}
@@ -176,45 +200,104 @@ func locateFTBAFolder() (string, error) {
 	}
 }
 
-func runNetworkChecks() []NetworkCheck {
+func runNetworkChecks(checks []CheckURLStruct) []NetworkCheck {
+	certificates := make(map[string]*CertificateSummary)
+	for _, check := range checks {
+		if host := tlsHost(check.URL); host != "" {
+			if _, ok := certificates[host]; !ok {
+				cert, err := inspectTLS(host)
+				if err != nil {
+					pterm.Debug.Printfln("Unable to inspect certificate for %s: %s", host, err)
+				}
+				certificates[host] = cert
+			}
+		}
+	}
+	nc := runChecks(checks, nil)
+	for i := range nc {
+		nc[i].Certificate = certificates[tlsHost(nc[i].URL)]
+	}
+	return nc
+}
+
+// runChecks runs each check using transport, or the default transport when nil
+func runChecks(checks []CheckURLStruct, transport http.RoundTripper) []NetworkCheck {
 	var nc []NetworkCheck
-	for url, checks := range checkRequestsURLs {
-		url = strings.Replace(url, "RANDOM_UUID", uuid.New().String(), 1)
-		client := &http.Client{}
-		req, err := http.NewRequest(checks.Method, url, nil)
+	for _, check := range checks {
+		url := strings.Replace(check.URL, "RANDOM_UUID", uuid.New().String(), 1)
+		client := &http.Client{Timeout: check.timeout(), Transport: transport}
+		var body io.Reader
+		if check.Body != "" {
+			body = strings.NewReader(check.Body)
+		}
+		req, err := http.NewRequest(check.Method, url, body)
 		if err != nil {
-			nc = append(nc, NetworkCheck{URL: url, Success: false, Error: true, Status: fmt.Sprintf("Error creating request to %s\n%s", url, err.Error())})
+			nc = append(nc, NetworkCheck{URL: url, Success: false, Error: true, Severity: check.Severity, Status: fmt.Sprintf("Error creating request to %s\n%s", url, err.Error())})
 			continue
 		}
+		for k, v := range check.Headers {
+			req.Header.Set(k, v)
+		}
 		resp, err := client.Do(req)
 		if err != nil {
-			nc = append(nc, NetworkCheck{URL: url, Success: false, Error: true, Status: fmt.Sprintf("Error making request to %s\n%s", url, err.Error())})
+			nc = append(nc, NetworkCheck{URL: url, Success: false, Error: true, Severity: check.Severity, Status: fmt.Sprintf("Error making request to %s\n%s", url, err.Error())})
 			continue
 		}
+		result := validateCheckResponse(url, check, resp)
+		if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+			result.clockSkew = time.Since(date)
+			result.hasDate = true
+		}
+		nc = append(nc, result)
+		resp.Body.Close()
+	}
+	return nc
+}
 
-		// DO checks
-		if resp.StatusCode != checks.ExpectedStatusCode {
-			nc = append(nc, NetworkCheck{URL: url, Success: false, Error: false, Status: fmt.Sprintf("%s: Expected %d got %d (%s)", url, checks.ExpectedStatusCode, resp.StatusCode, resp.Status)})
-			continue
+func validateCheckResponse(url string, check CheckURLStruct, resp *http.Response) NetworkCheck {
+	if !slices.Contains(check.ExpectedStatusCodes, resp.StatusCode) {
+		return NetworkCheck{URL: url, Success: false, Error: false, Severity: check.Severity, Status: fmt.Sprintf("%s: Expected %v got %d (%s)", url, check.ExpectedStatusCodes, resp.StatusCode, resp.Status)}
+	}
+	if check.ExpectedResponse != "" {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return NetworkCheck{URL: url, Success: false, Error: true, Severity: check.Severity, Status: fmt.Sprintf("Error reading response body\n%s", err.Error())}
 		}
-		if checks.ValidateResponse {
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				nc = append(nc, NetworkCheck{URL: url, Success: false, Error: true, Status: fmt.Sprintf("Error reading response body\n%s", err.Error())})
-				continue
-			}
-			match, err := regexp.Match(checks.ExpectedReponse, body)
-			if err != nil {
-				return nil
-			}
-			if !match {
-				nc = append(nc, NetworkCheck{URL: url, Success: false, Error: false, Status: fmt.Sprintf("Expected %s got %s", checks.ExpectedReponse, string(body))})
-				continue
-			}
+		match, err := regexp.Match(check.ExpectedResponse, body)
+		if err != nil {
+			return NetworkCheck{URL: url, Success: false, Error: true, Severity: check.Severity, Status: fmt.Sprintf("Invalid response regex for %s\n%s", url, err.Error())}
+		}
+		if !match {
+			return NetworkCheck{URL: url, Success: false, Error: false, Severity: check.Severity, Status: fmt.Sprintf("%s: Expected %s got %s", url, check.ExpectedResponse, string(body))}
 		}
-		nc = append(nc, NetworkCheck{URL: url, Success: true, Error: false, Status: "ok"})
 	}
-	return nc
+	return NetworkCheck{URL: url, Success: true, Error: false, Severity: check.Severity, Status: "ok"}
+}
+
+func printNetworkCheck(n NetworkCheck) {
+	if n.Success {
+		pterm.Success.Printfln("%s: %s", n.URL, n.Status)
+		return
+	}
+	switch n.Severity {
+	case "info":
+		pterm.Info.Println(n.Status)
+	case "warning":
+		pterm.Warning.Println(n.Status)
+	default:
+		if n.Error {
+			pterm.Error.Println(n.Status)
+		} else {
+			pterm.Warning.Println(n.Status)
+		}
+	}
+}
+
+// addRecommendation records a suggested fix for the final report
+func addRecommendation(recommendation string) {
+	if recommendation != "" && !slices.Contains(recommendations, recommendation) {
+		recommendations = append(recommendations, recommendation)
+	}
 }
 
 func isActiveProfileInProfiles(profiles Profiles) bool {
@@ -4,7 +4,10 @@ package dbg
 
 import (
 	"fmt"
+	"strings"
+
 	wmi "github.com/yusufpapurcu/wmi"
+	"golang.org/x/sys/windows/registry"
 )
 
 type (
@@ -25,3 +28,51 @@ func getSysInfo() (oSystem string, err error) {
 	oSystem = fmt.Sprintf("%s (%s)", dst[0].Caption, dst[0].Version)
 	return oSystem, nil
 }
+
+// getSystemProxy returns the WinINet proxy settings keyed by protocol and the
+// hosts in ProxyOverride that bypass the proxy
+func getSystemProxy() (map[string]string, []string, error) {
+	k, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Internet Settings`, registry.QUERY_VALUE)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer k.Close()
+
+	proxies := make(map[string]string)
+	if pac, _, err := k.GetStringValue("AutoConfigURL"); err == nil && pac != "" {
+		proxies["pac"] = pac
+	}
+	enabled, _, err := k.GetIntegerValue("ProxyEnable")
+	if err != nil || enabled == 0 {
+		return proxies, nil, nil
+	}
+	server, _, err := k.GetStringValue("ProxyServer")
+	if err != nil || server == "" {
+		return proxies, nil, nil
+	}
+	// "*.corp.example.com;10.*;<local>", <local> is every host without a dot
+	override, _, _ := k.GetStringValue("ProxyOverride")
+	bypass := splitNoProxy(override, ";")
+	// Either "host:port" for all protocols or "http=host:port;https=host:port"
+	if !strings.Contains(server, "=") {
+		proxies["http"] = server
+		return proxies, bypass, nil
+	}
+	for _, part := range strings.Split(server, ";") {
+		proto, addr, ok := strings.Cut(part, "=")
+		if !ok || addr == "" {
+			continue
+		}
+		if proto == "socks" {
+			addr = "socks5://" + addr
+		}
+		proxies[proto] = addr
+	}
+	return proxies, bypass, nil
+}
+
+// clipboardCommands lists the clipboard tools to try in order, each reading
+// from stdin
+func clipboardCommands() [][]string {
+	return [][]string{{"clip"}}
+}
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	ftbdbg "ftb-debug/v2/dbg"
 	"ftb-debug/v2/shared"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/eiannone/keyboard"
@@ -12,11 +15,57 @@ import (
 	"github.com/pterm/pterm/putils"
 )
 
+var options ftbdbg.Options
+
 func init() {
 	verboseLogging := flag.Bool("v", false, "Enable verbose logging")
 	noColours := flag.Bool("no-colours", false, "Disable colours in output")
+	flag.StringVar(&options.ChecksSource, "checks", "", "Path or URL to a JSON file of network checks to run instead of the built in checks")
+	dnsResolvers := flag.String("dns-resolvers", "", "Comma separated list of public DNS resolvers to compare answers against")
+	flag.BoolVar(&options.SpeedTest, "speed-test", false, "Measure download speed from the Mojang and Java runtime CDNs")
+	flag.DurationVar(&options.SpeedTestDuration, "speed-test-duration", 10*time.Second, "Maximum time to spend downloading from each speed test target")
+	speedTestURLs := flag.String("speed-test-urls", "", "Comma separated list of extra URLs to include in the speed test")
+	flag.StringVar(&options.NTPServer, "ntp", "", "NTP server to query first when checking the system clock, pool.ntp.org is used when no HTTP Date header can be read")
+	flag.DurationVar(&options.ClockSkewThreshold, "clock-skew-threshold", 2*time.Minute, "Warn when the system clock is off by more than this")
+	instanceDirs := flag.String("instances-dir", "", "Comma separated list of extra directories to search for instances")
+	flag.StringVar(&options.PackManifestDir, "pack-manifests", "", "Directory of FTB pack version file lists (<packId>/<versionId>.json) used to verify instances")
+	appPorts := flag.String("ports", "", "Comma separated list of extra localhost ports the app uses to check for conflicts")
+	flag.StringVar(&options.HTMLReport, "html", "", "Write a standalone HTML report of the results to this file, e.g. report.html")
+	flag.BoolVar(&options.Summary, "summary", false, "Print a short Markdown summary for pasting into Discord or GitHub issues")
+	flag.BoolVar(&options.CopySummary, "copy-summary", false, "Copy the Markdown summary to the clipboard")
+	flag.StringVar(&options.PasteURL, "paste-url", "", "Base URL of the paste server to upload to and read from (default https://pste.me)")
+	flag.Int64Var(&options.InlineLimit, "inline-limit", 0, "Embed files smaller than this many bytes in the manifest instead of uploading them, for example 2048. Consumers must understand schema version 3")
+	flag.StringVar(&options.InlineEncoding, "inline-encoding", "plain", "Encoding of files embedded in the manifest, plain or base64")
+	flag.Int64Var(&options.BatchLimit, "batch-limit", 0, "Combine files smaller than this many bytes into shared pastes, for example 131072. Consumers must understand schema version 3")
+	flag.Int64Var(&options.MaxFileSize, "max-file-size", 8<<20, "Largest file to upload in bytes, bigger files keep only their start and end, 0 for no limit")
+	flag.Int64Var(&options.UploadBudget, "upload-budget", 64<<20, "Total bytes to upload in one run, 0 for no limit")
+	flag.BoolVar(&options.Compress, "compress", false, "Compress uploads if the paste server supports it")
+	flag.IntVar(&options.LogCount, "log-count", 5, "Upload up to this many recent logs per folder as well as latest.log, debug.log and the newest crash report, 0 for no limit")
+	flag.IntVar(&options.LogDays, "log-days", 7, "Only upload logs modified within this many days, 0 for no limit")
+	flag.Usage = func() {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [command]\n\nCommands:\n  repair-settings        Back up and repair a corrupt settings.json\n  inspect <code|file>    Show a summary of a debug manifest and browse its logs\n  diff <before> <after>  Compare two debug manifests\n  schema [file]          Write the JSON Schema of the debug manifest\n\nFlags:\n", os.Args[0])
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
+	if *instanceDirs != "" {
+		options.InstanceDirs = strings.Split(*instanceDirs, ",")
+	}
+	for _, p := range strings.Split(*appPorts, ",") {
+		if port, err := strconv.Atoi(strings.TrimSpace(p)); err == nil {
+			options.AppPorts = append(options.AppPorts, port)
+		}
+	}
+	if *speedTestURLs != "" {
+		options.SpeedTestURLs = strings.Split(*speedTestURLs, ",")
+	}
+
+	if *dnsResolvers != "" {
+		options.DNSResolvers = strings.FieldsFunc(*dnsResolvers, func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+	}
+
 	if *verboseLogging {
 		pterm.EnableDebugMessages()
 	}
@@ -47,8 +96,18 @@ func init() {
 }
 
 func main() {
-
-	ftbdbg.RunDebug()
+	switch flag.Arg(0) {
+	case "repair-settings":
+		ftbdbg.RunRepairSettings(options)
+	case "inspect":
+		ftbdbg.RunInspect(options, flag.Arg(1))
+	case "diff":
+		ftbdbg.RunDiff(options, flag.Arg(1), flag.Arg(2))
+	case "schema":
+		ftbdbg.RunSchema(options, flag.Arg(1))
+	default:
+		ftbdbg.RunDebug(options)
+	}
 
 	pterm.Println(pterm.LightCyan("Press ESC to exit..."))
 